@@ -0,0 +1,104 @@
+package main
+
+import (
+	"cuelang.org/go/cue"
+)
+
+// cintAttribute is the field attribute schema authors use to configure how
+// cint reports a field, e.g. @cint(severity=warning, msg="use lowercase")
+const cintAttribute = "cint"
+
+// Severity describes how a validation error affects the result
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // Fails validation
+	SeverityWarning Severity = "warning" // Reported without failing validation
+)
+
+// fieldAttributes holds the options of a @cint(...) field attribute
+type fieldAttributes struct {
+	severity Severity
+	msg      string // Human message that replaces the CUE error message
+	ref      string // Path of the fields whose values this field refers to
+}
+
+// lookupFieldAttributes reads the @cint attribute of the field at path.
+// Fields without the attribute get the default options.
+func lookupFieldAttributes(v cue.Value, path cue.Path) fieldAttributes {
+	attrs := fieldAttributes{severity: SeverityError}
+	if !v.Exists() {
+		return attrs
+	}
+
+	field := v.LookupPath(path)
+	if !field.Exists() {
+		return attrs
+	}
+
+	attr := field.Attribute(cintAttribute)
+	if attr.Err() != nil {
+		return attrs
+	}
+
+	if severity, ok, _ := attr.Lookup(0, "severity"); ok && Severity(severity) == SeverityWarning {
+		attrs.severity = SeverityWarning
+	}
+	if msg, ok, _ := attr.Lookup(0, "msg"); ok {
+		attrs.msg = msg
+	}
+	if ref, ok, _ := attr.Lookup(0, "ref"); ok {
+		attrs.ref = ref
+	}
+	return attrs
+}
+
+// valuePath converts the path of a CUE error into a path within #Config
+func valuePath(errorPath []string) cue.Path {
+	var selectors []cue.Selector
+	for _, p := range errorPath {
+		if !isValidPathElement(p) {
+			continue
+		}
+		parsed := cue.ParsePath(p)
+		if parsed.Err() != nil {
+			selectors = append(selectors, cue.Str(p))
+			continue
+		}
+		selectors = append(selectors, parsed.Selectors()...)
+	}
+	return cue.MakePath(selectors...)
+}
+
+// deprecatedAttribute marks a schema field as deprecated, e.g. @deprecated("use spec.image instead")
+const deprecatedAttribute = "deprecated"
+
+// findDeprecatedFields reports a warning for every field set in config that is
+// marked with @deprecated in the schema, looked up in the unified value
+func findDeprecatedFields(config cue.Value, unified cue.Value) []ValidationError {
+	var warnings []ValidationError
+
+	walkFields(config, func(path []cue.Selector, field cue.Value) bool {
+		attr := unified.LookupPath(cue.MakePath(path...)).Attribute(deprecatedAttribute)
+		if attr.Err() != nil {
+			return true
+		}
+
+		problem := "field is deprecated"
+		if hint, err := attr.String(0); err == nil && hint != "" {
+			problem += ": " + hint
+		}
+
+		warnings = append(warnings, ValidationError{
+			File:     field.Pos().Filename(),
+			Line:     field.Pos().Line(),
+			Field:    selectorsToField(path),
+			Problem:  problem,
+			Code:     CodeDeprecatedField,
+			Severity: SeverityWarning,
+		})
+		return true
+	})
+
+	return warnings
+}
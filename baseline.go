@@ -0,0 +1,146 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Baseline is a set of known validation errors. Errors recorded in a baseline
+// are not reported again, so that strict schemas can be adopted in repositories
+// with existing violations while still failing on new ones.
+type Baseline struct {
+	counts map[baselineEntry]int
+}
+
+// baselineEntry identifies a known validation error independent of its line
+type baselineEntry struct {
+	File    string `json:"file"`
+	Field   string `json:"field,omitempty"`
+	Code    Code   `json:"code,omitempty"`
+	Problem string `json:"problem"`
+}
+
+// baselineFile is the on-disk format of a baseline
+type baselineFile struct {
+	Errors []baselineEntry `json:"errors"`
+}
+
+var (
+	positionPattern   = regexp.MustCompile(`:\d+(?::\d+)?`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// NewBaseline creates an empty baseline
+func NewBaseline() *Baseline {
+	return &Baseline{counts: make(map[baselineEntry]int)}
+}
+
+// LoadBaseline reads a baseline written by Write
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading baseline: %w", err)
+	}
+
+	var file baselineFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing baseline %s: %w", path, err)
+	}
+
+	b := NewBaseline()
+	for _, entry := range file.Errors {
+		b.counts[entry]++
+	}
+	return b, nil
+}
+
+// Add records all errors of a result
+func (b *Baseline) Add(result ValidationResult) {
+	for _, err := range result.Errors {
+		b.counts[newBaselineEntry(result.FileName, err)]++
+	}
+}
+
+// Filter removes the errors of a result that are recorded in the baseline.
+// Every recorded error matches at most one reported error. Entries written
+// before errors had codes match errors with any code. Results include the
+// missing fields that CUE hides behind other errors, so a new missing field in
+// a file with a recorded error is still reported.
+func (b *Baseline) Filter(result ValidationResult) ValidationResult {
+	var kept []ValidationError
+	for _, err := range result.Errors {
+		entry := newBaselineEntry(result.FileName, err)
+		if b.consume(entry) {
+			continue
+		}
+		entry.Code = ""
+		if b.consume(entry) {
+			continue
+		}
+		kept = append(kept, err)
+	}
+
+	filtered := createResult(result.FileName, kept)
+	filtered.OmittedErrors = result.OmittedErrors
+	return filtered
+}
+
+// consume removes one occurrence of entry and reports whether there was one
+func (b *Baseline) consume(entry baselineEntry) bool {
+	if b.counts[entry] == 0 {
+		return false
+	}
+	b.counts[entry]--
+	return true
+}
+
+// Write saves the baseline as JSON with entries in a stable order
+func (b *Baseline) Write(path string) error {
+	file := baselineFile{Errors: []baselineEntry{}}
+	for entry, count := range b.counts {
+		for range count {
+			file.Errors = append(file.Errors, entry)
+		}
+	}
+	slices.SortFunc(file.Errors, func(a, b baselineEntry) int {
+		return cmp.Or(
+			cmp.Compare(a.File, b.File),
+			cmp.Compare(a.Field, b.Field),
+			cmp.Compare(a.Code, b.Code),
+			cmp.Compare(a.Problem, b.Problem),
+		)
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing baseline: %w", err)
+	}
+	return nil
+}
+
+// newBaselineEntry creates the baseline entry for an error in a file
+func newBaselineEntry(fileName string, err ValidationError) baselineEntry {
+	return baselineEntry{
+		File:    filepath.ToSlash(filepath.Clean(fileName)),
+		Field:   err.Field,
+		Code:    err.Code,
+		Problem: normalizeProblem(err.Problem),
+	}
+}
+
+// normalizeProblem removes positions and redundant whitespace from an error
+// message so that entries survive unrelated edits of the file
+func normalizeProblem(problem string) string {
+	problem = positionPattern.ReplaceAllString(problem, "")
+	problem = whitespacePattern.ReplaceAllString(problem, " ")
+	return strings.TrimSpace(problem)
+}
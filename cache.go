@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// resultCache stores validation results on disk, keyed by a hash of everything
+// that can influence them: the cint build, the schema, the check options
+// including the content of data and values files, and the config file.
+// A nil *resultCache is valid and caches nothing.
+type resultCache struct {
+	dir  string
+	base []byte // Hash of the inputs shared by every file
+}
+
+// newResultCache creates a cache in dir for results validated against schemaPath
+// with checks. It returns nil when dir is empty.
+func newResultCache(dir string, schemaPath string, checks checkOptions) (*resultCache, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	schemaData, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	writeHashField(h, []byte(version))
+	writeHashField(h, buildID())
+	writeHashField(h, schemaData)
+	writeHashField(h, []byte(fmt.Sprintf("%+v", checks)))
+	dataPaths := slices.Clone(checks.values)
+	for _, d := range checks.data {
+		_, dataPath, _ := strings.Cut(d, "=")
+		dataPaths = append(dataPaths, dataPath)
+	}
+	for _, dataPath := range dataPaths {
+		dataBytes, err := os.ReadFile(dataPath)
+		if err != nil {
+			return nil, err
+		}
+		writeHashField(h, dataBytes)
+	}
+
+	return &resultCache{dir: dir, base: h.Sum(nil)}, nil
+}
+
+// buildID identifies the cint binary, so that results cached by another build
+// of the same version are not reused: the module checksum of installed
+// releases, the revision of builds from a clean checkout and otherwise a hash
+// of the executable
+var buildID = sync.OnceValue(func() []byte {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Sum != "" {
+			return []byte(info.Main.Version + " " + info.Main.Sum)
+		}
+		settings := make(map[string]string)
+		for _, setting := range info.Settings {
+			settings[setting.Key] = setting.Value
+		}
+		if revision := settings["vcs.revision"]; revision != "" && settings["vcs.modified"] == "false" {
+			return []byte(revision)
+		}
+	}
+	return executableHash()
+})
+
+// executableHash returns the hash of the running executable, or nil if it
+// cannot be read
+func executableHash() []byte {
+	path, err := os.Executable()
+	if err != nil {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil
+	}
+	return h.Sum(nil)
+}
+
+// defaultCacheDir returns the per-user cache directory for cint
+func defaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cint"), nil
+}
+
+// key computes the cache key for config files validated as a single config
+func (c *resultCache) key(files ...configFile) string {
+	if c == nil {
+		return ""
+	}
+
+	h := sha256.New()
+	h.Write(c.base)
+	for _, file := range files {
+		writeHashField(h, []byte(file.path))
+		writeHashField(h, file.data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns the cached result for key
+func (c *resultCache) get(key string) (ValidationResult, bool) {
+	if c == nil {
+		return ValidationResult{}, false
+	}
+
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return ValidationResult{}, false
+	}
+
+	var result ValidationResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return ValidationResult{}, false
+	}
+	return result, true
+}
+
+// put stores result under key. Failures are ignored because the cache is only an optimization.
+func (c *resultCache) put(key string, result ValidationResult) {
+	if c == nil {
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	// Write to a temporary file first so concurrent runs never read a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		return
+	}
+	os.Rename(tmp.Name(), path)
+}
+
+// path returns the location of the cache entry for key
+func (c *resultCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// writeHashField writes a length-prefixed field so that adjacent fields cannot run into each other
+func writeHashField(w io.Writer, data []byte) {
+	w.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(data))))
+	w.Write(data)
+}
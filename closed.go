@@ -0,0 +1,116 @@
+package main
+
+import (
+	"slices"
+
+	"cuelang.org/go/cue"
+)
+
+// Closedness controls how fields that the schema does not declare are reported
+type Closedness string
+
+const (
+	ClosednessOpen    Closedness = "open"    // Fields not allowed by closed definitions are warnings
+	ClosednessDefault Closedness = "default" // Closed definitions are enforced as written
+	ClosednessClosed  Closedness = "closed"  // Structs opened with ... are treated as closed too
+)
+
+// closednessModes lists the supported modes in the order they are documented
+var closednessModes = []Closedness{ClosednessOpen, ClosednessDefault, ClosednessClosed}
+
+// parseClosedness parses a closedness mode, where an empty string is the default mode
+func parseClosedness(s string) (Closedness, error) {
+	return parseMode("closedness", s, closednessModes, ClosednessDefault)
+}
+
+// findUnknownFields returns the paths of the fields set in config that the
+// definition does not allow, without the fields below them. In open mode they
+// are removed before validation, so that they cannot hide other errors.
+func findUnknownFields(config cue.Value, definition cue.Value) [][]cue.Selector {
+	var unknown [][]cue.Selector
+
+	walkFields(config, func(path []cue.Selector, field cue.Value) bool {
+		name := path[len(path)-1]
+		if name.LabelType() != cue.StringLabel {
+			return true
+		}
+
+		parent, ok := lookupSchemaValue(definition, path[:len(path)-1])
+		if !ok || parent.IncompleteKind() != cue.StructKind || parent.Allows(name) {
+			return true
+		}
+		unknown = append(unknown, path)
+		return false
+	})
+
+	return unknown
+}
+
+// unknownFieldWarnings reports a warning for every unknown field, so that
+// unknown fields can be surveyed before they are enforced
+func unknownFieldWarnings(config cue.Value, definition cue.Value, unknown [][]cue.Selector) []ValidationError {
+	warnings := make([]ValidationError, len(unknown))
+	for i, path := range unknown {
+		warnings[i] = fieldNotAllowedError(config, definition, path)
+		warnings[i].Severity = SeverityWarning
+	}
+	return warnings
+}
+
+// downgradeUnknownFields turns the errors for fields that are not allowed into
+// warnings, for fields findUnknownFields cannot locate in the schema
+func downgradeUnknownFields(validationErrors []ValidationError) []ValidationError {
+	for i := range validationErrors {
+		if validationErrors[i].Code == CodeFieldNotAllowed {
+			validationErrors[i].Severity = SeverityWarning
+		}
+	}
+	return validationErrors
+}
+
+// findUndeclaredFields reports an error for every field set in config that is
+// only accepted because its struct in the schema is open. Fields matching a
+// pattern constraint such as [string]: int are declared by the pattern.
+func findUndeclaredFields(config cue.Value, definition cue.Value) []ValidationError {
+	var problems []ValidationError
+
+	walkFields(config, func(path []cue.Selector, field cue.Value) bool {
+		name := path[len(path)-1]
+		if name.LabelType() != cue.StringLabel {
+			return true
+		}
+
+		parent, ok := lookupSchemaValue(definition, path[:len(path)-1])
+		if !ok || parent.IncompleteKind() != cue.StructKind || !parent.Allows(name) {
+			return true
+		}
+		if slices.Contains(allowedFieldNames(definition, path[:len(path)-1]), name.Unquoted()) {
+			return true
+		}
+		if pattern := parent.LookupPath(cue.MakePath(cue.AnyString)); pattern.Exists() && pattern.IncompleteKind() != cue.TopKind {
+			return true
+		}
+
+		problems = append(problems, fieldNotAllowedError(config, definition, path))
+		return false
+	})
+
+	return problems
+}
+
+// fieldNotAllowedError creates the error for a field of config at path that
+// the definition does not declare, with a suggestion for a misspelled name
+func fieldNotAllowedError(config cue.Value, definition cue.Value, path []cue.Selector) ValidationError {
+	problem := "#Config." + selectorsToField(path) + ": field not allowed"
+	if hint := fieldSuggestion(definition, selectorStrings(path)); hint != "" {
+		problem += "; " + hint
+	}
+	pos := enclosingPos(config, path)
+	return ValidationError{
+		File:    pos.Filename(),
+		Line:    pos.Line(),
+		Field:   selectorsToField(path),
+		Problem: problem,
+		Code:    CodeFieldNotAllowed,
+	}
+}
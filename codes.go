@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue/errors"
+)
+
+// Code is a stable identifier for a class of validation errors. Unlike the
+// English text of CUE errors, codes do not change between versions and can be
+// used in suppressions, baselines and documentation.
+type Code string
+
+const (
+	CodeUnsupportedFormat Code = "CINT001" // Config file extension is not supported
+	CodeReadFailure       Code = "CINT002" // Config file could not be read
+	CodeSchemaLoad        Code = "CINT003" // Schema could not be read or compiled
+	CodeSchemaNoConfig    Code = "CINT004" // Schema does not define #Config
+
+	CodeTypeMismatch       Code = "CINT010" // Value has the wrong type
+	CodeOutOfBound         Code = "CINT011" // Value violates a bound such as >=1
+	CodeRegexMismatch      Code = "CINT012" // Value does not match a =~ or !~ pattern
+	CodeFieldNotAllowed    Code = "CINT013" // Field is not allowed by a closed struct
+	CodeMissingField       Code = "CINT014" // Required field is missing or not concrete
+	CodeNoAlternative      Code = "CINT015" // Value matches no alternative of a disjunction
+	CodeConflictingValues  Code = "CINT016" // Value conflicts with a schema constant
+	CodeDefaultValue       Code = "CINT017" // Field relies on a default value (strict concreteness)
+	CodeDanglingReference  Code = "CINT018" // Value refers to something no config defines
+	CodeValidationFailure  Code = "CINT019" // Any other schema violation
+	CodeParseError         Code = "CINT020" // Config file is not valid YAML or JSON
+	CodeUnsetVariable      Code = "CINT021" // Placeholder refers to an unset environment variable
+	CodeTemplateError      Code = "CINT022" // Config template could not be rendered
+	CodeUnusedSuppression  Code = "CINT030" // Suppression comment matched no error
+	CodeInvalidSuppression Code = "CINT031" // Suppression comment is malformed
+	CodeDeprecatedField    Code = "CINT040" // Field is marked with @deprecated
+)
+
+// classifyError returns the code for a CUE validation error based on its
+// message format, which is stable where the formatted text is not
+func classifyError(e errors.Error) Code {
+	format, args := e.Msg()
+
+	switch {
+	case format == "field not allowed":
+		return CodeFieldNotAllowed
+	case format == "field is required but not present",
+		strings.HasPrefix(format, "incomplete value"),
+		strings.HasPrefix(format, "non-concrete value"):
+		return CodeMissingField
+	case isEmptyDisjunction(e):
+		return CodeNoAlternative
+	case strings.Contains(format, "(mismatched types"):
+		return CodeTypeMismatch
+	case strings.Contains(format, "(out of bound"):
+		if len(args) > 1 && isRegexBound(fmt.Sprint(args[len(args)-1])) {
+			return CodeRegexMismatch
+		}
+		return CodeOutOfBound
+	case strings.HasPrefix(format, "conflicting values"):
+		return CodeConflictingValues
+	default:
+		return CodeValidationFailure
+	}
+}
+
+// isRegexBound checks if a bound is a regular expression constraint
+func isRegexBound(bound string) bool {
+	return strings.HasPrefix(bound, "=~") || strings.HasPrefix(bound, "!~")
+}
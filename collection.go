@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"strings"
+
+	"cuelang.org/go/cue"
+)
+
+// collectionDefinition is the schema definition that receives every valid
+// config of a run at once, keyed by file name, to check invariants across files
+const collectionDefinition = "#Collection"
+
+// collection gathers the valid configs of a run and validates them together
+// against #Collection and the @cint(ref=...) fields of #Config once every file
+// has been validated on its own
+type collection struct {
+	v             *validator
+	merge         []string
+	entries       []collectionEntry
+	hasDefinition bool // Schema defines #Collection
+	hasReferences bool // #Config has fields marked with @cint(ref=...)
+}
+
+// collectionEntry is a config of the collection and the files it was parsed from
+type collectionEntry struct {
+	name    string
+	paths   []string
+	context bool // Config is not validated in this run and only completes the collection
+}
+
+// newCollection returns a collection if the schema defines #Collection or
+// reference fields and nil otherwise. The schema is only compiled when its
+// source mentions either, so that runs answered from the cache do not compile
+// schemas without cross-file checks. Config files are read with readFile.
+func newCollection(schemaPath string, checks checkOptions, merge []string, readFile func(string) ([]byte, error)) *collection {
+	schemaData, err := os.ReadFile(schemaPath)
+	if err != nil || !bytes.Contains(schemaData, []byte(collectionDefinition)) && !bytes.Contains(schemaData, []byte("ref=")) {
+		return nil
+	}
+
+	v := newValidator(schemaPath, checks, nil)
+	v.readFile = readFile
+	if v.ensureSchema() != nil {
+		return nil
+	}
+	c := &collection{
+		v:             v,
+		merge:         merge,
+		hasDefinition: v.schema.LookupPath(cue.ParsePath(collectionDefinition)).Exists(),
+		hasReferences: hasReferences(v.schema.LookupPath(cue.ParsePath("#Config")), 0),
+	}
+	if !c.hasDefinition && !c.hasReferences {
+		return nil
+	}
+	return c
+}
+
+// add records the config of a result if it is valid. Invalid configs are left
+// out so that their errors are not reported a second time.
+func (c *collection) add(result ValidationResult) {
+	if c == nil || !result.IsValid {
+		return
+	}
+
+	paths := []string{result.FileName}
+	if len(c.merge) > 0 && result.FileName == mergedName(c.merge) {
+		paths = c.merge
+	}
+	c.entries = append(c.entries, collectionEntry{name: result.FileName, paths: paths})
+}
+
+// addContext records config files that are not validated in this run, such as
+// the unchanged files in git mode, so that rules across files and references
+// also see them.
+// Their errors are not reported, and configs that fail #Config are left out.
+func (c *collection) addContext(paths []string) {
+	if c == nil {
+		return
+	}
+	for _, path := range paths {
+		c.entries = append(c.entries, collectionEntry{name: path, paths: []string{path}, context: true})
+	}
+}
+
+// validate checks the references between the recorded configs and unifies
+// #Collection with a struct of the configs keyed by their file name. The errors
+// of both are reported as a result named #Collection.
+func (c *collection) validate() ValidationResult {
+	ctx := c.v.ctx
+	configs := ctx.CompileString("{}")
+	configDefinition := c.v.schema.LookupPath(cue.ParsePath("#Config"))
+
+	// Workers finish in any order, so sort the entries to get stable errors
+	slices.SortFunc(c.entries, func(a, b collectionEntry) int { return strings.Compare(a.name, b.name) })
+
+	var files []string
+	var refs referenceIndex
+	var unifiedConfigs []cue.Value
+	for _, entry := range c.entries {
+		config, ok := c.parseEntry(entry)
+		if !ok {
+			continue
+		}
+		unified := configDefinition.Unify(config)
+		if entry.context && unified.Validate(c.v.checks.concreteness.validateOptions()...) != nil {
+			continue
+		}
+		configs = configs.FillPath(cue.MakePath(cue.Str(entry.name)), config)
+		files = append(files, entry.paths...)
+
+		// Unchanged files define values, but their own references are not checked
+		if !entry.context {
+			refs.add(config, unified)
+		}
+		unifiedConfigs = append(unifiedConfigs, unified)
+	}
+	for _, unified := range unifiedConfigs {
+		refs.define(unified)
+	}
+	validationErrors := refs.dangling()
+
+	if c.hasDefinition {
+		validationErrors = append(validationErrors, c.validateDefinition(configs, files)...)
+	}
+	return createResult(collectionDefinition, validationErrors)
+}
+
+// validateDefinition unifies #Collection with configs, a struct of the configs
+// keyed by their file name, and reports the errors of a single config in its file
+func (c *collection) validateDefinition(configs cue.Value, files []string) []ValidationError {
+	definition := c.v.schema.LookupPath(cue.ParsePath(collectionDefinition))
+	unified := definition.Unify(configs)
+	err := unified.Validate(c.v.checks.concreteness.validateOptions()...)
+	if err == nil {
+		return nil
+	}
+
+	vc := validationContext{fileName: collectionDefinition, files: files, config: configs, definition: definition, unified: unified}
+	validationErrors := extractValidationErrors(err, vc)
+
+	// Longer names first, so that a name is not mistaken for a prefix of another
+	entries := slices.Clone(c.entries)
+	slices.SortFunc(entries, func(a, b collectionEntry) int { return len(b.name) - len(a.name) })
+	for i := range validationErrors {
+		attributeToEntry(&validationErrors[i], entries)
+	}
+
+	// Errors located in a file outside the run are left to the run that changes it
+	return slices.DeleteFunc(validationErrors, func(ve ValidationError) bool {
+		return slices.ContainsFunc(c.entries, func(entry collectionEntry) bool {
+			return entry.context && ve.File == entry.paths[0]
+		})
+	})
+}
+
+// parseEntry parses and merges the files of an entry in the context of the collection
+func (c *collection) parseEntry(entry collectionEntry) (cue.Value, bool) {
+	var config cue.Value
+	for i, path := range entry.paths {
+		configData, err := c.v.readFile(path)
+		if err != nil {
+			return cue.Value{}, false
+		}
+		prepared, err := prepareConfig(c.v.ctx, c.v.checks, path, configData)
+		if err != nil {
+			return cue.Value{}, false
+		}
+		parsed, err := parseConfigFile(c.v.ctx, path, prepared.data)
+		if err != nil {
+			return cue.Value{}, false
+		}
+		if i == 0 {
+			config = parsed
+		} else {
+			config = c.v.checks.merge.mergeConfig(config, parsed)
+		}
+	}
+	return config, true
+}
+
+// attributeToEntry removes the file name key from the field of an error below
+// a single config and reports the error in that file when it has no location
+func attributeToEntry(ve *ValidationError, entries []collectionEntry) {
+	for _, entry := range entries {
+		if ve.Field != entry.name && !strings.HasPrefix(ve.Field, entry.name+".") {
+			continue
+		}
+		ve.Field = strings.TrimPrefix(strings.TrimPrefix(ve.Field, entry.name), ".")
+		if ve.File == "" && len(entry.paths) == 1 {
+			ve.File = entry.paths[0]
+		}
+		return
+	}
+}
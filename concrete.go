@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue"
+)
+
+// Concreteness controls how complete a config must be to pass validation
+type Concreteness string
+
+const (
+	ConcretenessLenient Concreteness = "lenient" // Missing fields are allowed, e.g. for base files merged later
+	ConcretenessDefault Concreteness = "default" // Every field must be set or have a default
+	ConcretenessStrict  Concreteness = "strict"  // Every field must be set explicitly, defaults are not used
+)
+
+// concretenessModes lists the supported modes in the order they are documented
+var concretenessModes = []Concreteness{ConcretenessLenient, ConcretenessDefault, ConcretenessStrict}
+
+// parseConcreteness parses a concreteness mode, where an empty string is the default mode
+func parseConcreteness(s string) (Concreteness, error) {
+	return parseMode("concreteness", s, concretenessModes, ConcretenessDefault)
+}
+
+// parseMode parses one of the modes of an option, returning fallback for an empty string
+func parseMode[T ~string](option string, s string, modes []T, fallback T) (T, error) {
+	if s == "" {
+		return fallback, nil
+	}
+	for _, mode := range modes {
+		if T(s) == mode {
+			return mode, nil
+		}
+	}
+
+	names := make([]string, len(modes))
+	for i, mode := range modes {
+		names[i] = string(mode)
+	}
+	return "", fmt.Errorf("invalid %s %q (supported: %s)", option, s, strings.Join(names, ", "))
+}
+
+// validateOptions returns the options for Validate in the given mode
+func (c Concreteness) validateOptions() []cue.Option {
+	if c == ConcretenessLenient {
+		return nil
+	}
+	return []cue.Option{cue.Concrete(true)}
+}
+
+// findDefaultedFields reports an error for every field that is not set in the
+// config and only gets its value from a default in the schema. These are the
+// fields that -show-defaults fills in, including optional fields with a default.
+func findDefaultedFields(config cue.Value, unified cue.Value) []ValidationError {
+	var problems []ValidationError
+
+	for _, d := range collectDefaults(unified) {
+		pos := enclosingPos(config, d.path)
+		problems = append(problems, ValidationError{
+			File:    pos.Filename(),
+			Line:    pos.Line(),
+			Field:   selectorsToField(d.path),
+			Problem: fmt.Sprintf("field is not set and relies on the default value %v", d.value),
+			Code:    CodeDefaultValue,
+		})
+	}
+
+	return problems
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffOp is a single line of a line-based diff
+type diffOp struct {
+	kind     byte // ' ' for a common line, '-' for a removed line, '+' for an added line
+	text     string
+	oldIndex int // Number of old lines before this line
+	newIndex int // Number of new lines before this line
+}
+
+// diffLines computes a line diff of a and b from their longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', text: a[i], oldIndex: i, newIndex: j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{kind: '-', text: a[i], oldIndex: i, newIndex: j})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', text: b[j], oldIndex: i, newIndex: j})
+			j++
+		}
+	}
+	return ops
+}
+
+// unifiedDiff formats the differences between a and b as a unified diff with
+// the given number of context lines, or returns "" when they are equal
+func unifiedDiff(oldName string, newName string, a, b []string, context int) string {
+	ops := diffLines(a, b)
+
+	var changes []int
+	for i, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	for first := 0; first < len(changes); {
+		// Extend the hunk while the next change is close enough to share context
+		last := first
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*context {
+			last++
+		}
+
+		start := max(0, changes[first]-context)
+		end := min(len(ops), changes[last]+context+1)
+		writeHunk(&out, ops[start:end])
+		first = last + 1
+	}
+
+	return out.String()
+}
+
+// writeHunk writes a hunk header followed by the lines of the hunk
+func writeHunk(out *strings.Builder, ops []diffOp) {
+	oldCount, newCount := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n",
+		hunkRange(ops[0].oldIndex, oldCount), hunkRange(ops[0].newIndex, newCount))
+	for _, op := range ops {
+		fmt.Fprintf(out, "%c%s\n", op.kind, op.text)
+	}
+}
+
+// hunkRange formats the start line and line count of one side of a hunk. An
+// empty range starts at the line before it, as in diff -u.
+func hunkRange(index int, count int) string {
+	start := index + 1
+	if count == 0 {
+		start = index
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb",
+			b:    "a\nb",
+			want: "",
+		},
+		{
+			name: "separate hunks",
+			a:    "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk",
+			b:    "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl",
+			want: "--- old\n+++ new\n@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n@@ -9,3 +9,4 @@\n i\n j\n k\n+l\n",
+		},
+		{
+			name: "insert into empty",
+			a:    "",
+			b:    "a",
+			want: "--- old\n+++ new\n@@ -1 +1 @@\n-\n+a\n",
+		},
+		{
+			name: "pure insertion",
+			a:    "a\nb",
+			b:    "a\nx\nb",
+			want: "--- old\n+++ new\n@@ -1,2 +1,3 @@\n a\n+x\n b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedDiff("old", "new", strings.Split(tt.a, "\n"), strings.Split(tt.b, "\n"), 3)
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/token"
+)
+
+// isEmptyDisjunction checks if a CUE error is the header CUE emits before the
+// errors of every alternative of a failed disjunction
+func isEmptyDisjunction(e errors.Error) bool {
+	format, _ := e.Msg()
+	return format == "%d errors in empty disjunction:"
+}
+
+// disjunctionDetails returns the errors following a disjunction header that
+// belong to it, i.e. the errors at or below the path of the header
+func disjunctionDetails(header errors.Error, following []errors.Error) []errors.Error {
+	n := 0
+	for n < len(following) && hasPathPrefix(following[n].Path(), header.Path()) {
+		n++
+	}
+	return following[:n]
+}
+
+// summarizeDisjunction replaces a failed disjunction and the errors of all its
+// alternatives with a single error listing the allowed values or the closest
+// alternative with its specific failures
+func summarizeDisjunction(header errors.Error, details []errors.Error, vc validationContext) ValidationError {
+	ve := extractSingleError(header, vc)
+	for _, d := range details {
+		if ve.Line > 0 {
+			break
+		}
+		pos := extractPosition(d, vc.files)
+		ve.File, ve.Line = pos.Filename(), pos.Line()
+	}
+
+	summary := strings.Join(header.Path(), ".") + ": " + describeDisjunction(header, details, vc)
+	if ve.Detail != "" {
+		ve.Detail = summary
+	} else {
+		ve.Problem = summary
+	}
+	return ve
+}
+
+// describeDisjunction explains why a value failed a disjunction
+func describeDisjunction(header errors.Error, details []errors.Error, vc validationContext) string {
+	path := valuePath(header.Path())
+	value := vc.config.LookupPath(path)
+	schemaValue, _ := lookupSchemaValue(vc.definition, path.Selectors())
+	alternatives := disjunctionAlternatives(schemaValue)
+	if alternatives == nil {
+		alternatives = concreteAlternatives(schemaValue)
+	}
+
+	if value.Exists() && isScalar(value) && len(alternatives) > 0 {
+		return fmt.Sprintf("invalid value %v (must be one of %s)", value, strings.Join(alternatives, ", "))
+	}
+
+	if name, problems, ok := closestAlternative(schemaValue, value, alternatives, header.Path()); ok {
+		return fmt.Sprintf("no alternative matched, closest is %s: %s", name, strings.Join(problems, "; "))
+	}
+
+	var problems []string
+	for _, d := range details {
+		problems = append(problems, relativeMessage(d, header.Path()))
+	}
+	return "no alternative matched: " + strings.Join(problems, "; ")
+}
+
+// disjunctionAlternatives returns the alternatives of a disjunction as written
+// in the schema, without default markers and duplicates
+func disjunctionAlternatives(schemaValue cue.Value) []string {
+	field, ok := schemaValue.Source().(*ast.Field)
+	if !ok {
+		return nil
+	}
+
+	var alternatives []string
+	for _, expr := range flattenOr(field.Value) {
+		if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.MUL {
+			expr = unary.X
+		}
+		formatted, err := format.Node(expr)
+		if err != nil {
+			return nil
+		}
+		if alt := string(formatted); !slices.Contains(alternatives, alt) {
+			alternatives = append(alternatives, alt)
+		}
+	}
+
+	if len(alternatives) < 2 {
+		return nil
+	}
+	return alternatives
+}
+
+// concreteAlternatives returns the alternatives of a disjunction that is not
+// written with |, such as or([for t in teams {t.name}]), when all of them are
+// concrete values
+func concreteAlternatives(schemaValue cue.Value) []string {
+	op, args := schemaValue.Expr()
+	if op != cue.OrOp {
+		return nil
+	}
+
+	var alternatives []string
+	for _, arg := range args {
+		if !arg.IsConcrete() {
+			return nil
+		}
+		if alt := fmt.Sprint(arg); !slices.Contains(alternatives, alt) {
+			alternatives = append(alternatives, alt)
+		}
+	}
+
+	if len(alternatives) < 2 {
+		return nil
+	}
+	return alternatives
+}
+
+// flattenOr splits a chain of | operators into its operands
+func flattenOr(expr ast.Expr) []ast.Expr {
+	if paren, ok := expr.(*ast.ParenExpr); ok {
+		return flattenOr(paren.X)
+	}
+	binary, ok := expr.(*ast.BinaryExpr)
+	if !ok || binary.Op != token.OR {
+		return []ast.Expr{expr}
+	}
+	return append(flattenOr(binary.X), flattenOr(binary.Y)...)
+}
+
+// closestAlternative unifies value with every alternative of the schema
+// disjunction and returns the one with the fewest errors together with them,
+// formatted relative to the disjunction at basePath
+func closestAlternative(schemaValue cue.Value, value cue.Value, names []string, basePath []string) (string, []string, bool) {
+	if !value.Exists() {
+		return "", nil, false
+	}
+	op, args := schemaValue.Expr()
+	if op != cue.OrOp {
+		return "", nil, false
+	}
+
+	var bestName string
+	var bestErrors []errors.Error
+	for i, alternative := range args {
+		err := alternative.Unify(value).Validate(cue.Concrete(true))
+		if err == nil {
+			continue
+		}
+		errs := errors.Errors(err)
+		if bestErrors != nil && len(errs) >= len(bestErrors) {
+			continue
+		}
+
+		bestName, bestErrors = fmt.Sprintf("alternative %d", i+1), errs
+		if len(names) == len(args) {
+			bestName = names[i]
+		}
+	}
+	if bestErrors == nil {
+		return "", nil, false
+	}
+
+	var problems []string
+	for _, e := range bestErrors {
+		problems = append(problems, relativeMessage(e, basePath))
+	}
+	return bestName, problems, true
+}
+
+// relativeMessage formats an error message with its path relative to base
+func relativeMessage(e errors.Error, base []string) string {
+	format, args := e.Msg()
+	msg := fmt.Sprintf(format, args...)
+
+	path := e.Path()
+	if hasPathPrefix(path, base) {
+		path = path[len(base):]
+	}
+	if field := formatPath(path); field != "" {
+		return field + ": " + msg
+	}
+	return msg
+}
+
+// hasPathPrefix checks if an error path starts with prefix
+func hasPathPrefix(path []string, prefix []string) bool {
+	return len(path) >= len(prefix) && slices.Equal(path[:len(prefix)], prefix)
+}
+
+// isScalar checks if a value is neither a struct nor a list
+func isScalar(v cue.Value) bool {
+	kind := v.IncompleteKind()
+	return kind != cue.StructKind && kind != cue.ListKind
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+
+	"cuelang.org/go/cue"
+)
+
+// EnvMode controls how ${VAR} placeholders in config files are handled
+type EnvMode string
+
+const (
+	EnvOff         EnvMode = "off"         // Placeholders are validated as the strings they are
+	EnvExpand      EnvMode = "expand"      // Placeholders are replaced by environment variables before validation
+	EnvPlaceholder EnvMode = "placeholder" // Placeholders satisfy the constraints of any scalar field
+)
+
+// envModes lists the supported modes in the order they are documented
+var envModes = []EnvMode{EnvOff, EnvExpand, EnvPlaceholder}
+
+// parseEnvMode parses an env mode, where an empty string is the default mode
+func parseEnvMode(s string) (EnvMode, error) {
+	return parseMode("env", s, envModes, EnvOff)
+}
+
+// placeholderPattern matches ${VAR} and ${VAR:-default}
+var placeholderPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// expandEnv replaces the placeholders in config data with environment
+// variables, using the default of a placeholder when its variable is unset or
+// empty. Lines are expanded one at a time, so that errors keep the line of the
+// placeholder as long as no value contains a line break. A placeholder of an
+// unset variable without default is replaced by an empty string and reported.
+func expandEnv(configPath string, configData []byte) ([]byte, []ValidationError) {
+	var problems []ValidationError
+	var expanded bytes.Buffer
+
+	for i, line := range bytes.SplitAfter(configData, []byte("\n")) {
+		expanded.Write(placeholderPattern.ReplaceAllFunc(line, func(placeholder []byte) []byte {
+			match := placeholderPattern.FindSubmatch(placeholder)
+			if value := os.Getenv(string(match[1])); value != "" {
+				return []byte(value)
+			}
+			if bytes.Contains(placeholder, []byte(":-")) {
+				return match[2]
+			}
+			if _, ok := os.LookupEnv(string(match[1])); !ok {
+				problems = append(problems, ValidationError{
+					File:    configPath,
+					Line:    i + 1,
+					Problem: fmt.Sprintf("environment variable `%s` is not set", match[1]),
+					Code:    CodeUnsetVariable,
+				})
+			}
+			return nil
+		}))
+	}
+
+	return expanded.Bytes(), problems
+}
+
+// prepare returns the config data to parse in mode m, together with the
+// problems found while expanding placeholders
+func (m EnvMode) prepare(configPath string, configData []byte) ([]byte, []ValidationError) {
+	if m != EnvExpand {
+		return configData, nil
+	}
+	return expandEnv(configPath, configData)
+}
+
+// cacheFiles returns the files to derive a cache key from. Expanded files
+// depend on the environment, so their key is derived from the expanded data.
+func (m EnvMode) cacheFiles(files []configFile) []configFile {
+	if m != EnvExpand {
+		return files
+	}
+	expanded := make([]configFile, len(files))
+	for i, file := range files {
+		data, _ := expandEnv(file.path, file.data)
+		expanded[i] = configFile{path: file.path, data: data}
+	}
+	return expanded
+}
+
+// findPlaceholderFields returns the paths of the fields whose value contains a
+// placeholder, when the schema accepts a scalar for the field. The value is only
+// known once the placeholder has been replaced, so the fields are removed from
+// the config before validation instead of being validated as strings.
+func findPlaceholderFields(config cue.Value, definition cue.Value) [][]cue.Selector {
+	var placeholders [][]cue.Selector
+	walkFields(config, func(path []cue.Selector, field cue.Value) bool {
+		s, err := field.String()
+		if err != nil || !placeholderPattern.MatchString(s) {
+			return true
+		}
+		if schema, ok := lookupSchemaValue(definition, path); ok && schema.IncompleteKind()&^(cue.StructKind|cue.ListKind) != 0 {
+			placeholders = append(placeholders, path)
+		}
+		return true
+	})
+	return placeholders
+}
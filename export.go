@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/encoding/yaml"
+)
+
+// exportFormats lists the output formats supported by cint export
+var exportFormats = []string{"yaml", "json"}
+
+// defaultField is a field that gets its value from a default in the schema
+type defaultField struct {
+	path  []cue.Selector
+	value cue.Value
+}
+
+// defaultedConfig is a valid config together with the defaults the schema applies to it
+type defaultedConfig struct {
+	config   cue.Value // The parsed config
+	unified  cue.Value // Config unified with #Config
+	defaults []defaultField
+}
+
+// loadDefaultedConfig validates a config file and collects the defaults that
+// the schema applies to it. The returned result holds the errors when the
+// config is not valid.
+func loadDefaultedConfig(v *validator, configPath string) (defaultedConfig, ValidationResult) {
+	configData, err := os.ReadFile(configPath)
+	if err != nil {
+		return defaultedConfig{}, createErrorResult(configPath, CodeReadFailure, fmt.Sprintf("failed to read file: %v", err))
+	}
+	if err := v.ensureSchema(); err != nil {
+		return defaultedConfig{}, createErrorResult(configPath, CodeSchemaLoad, fmt.Sprintf("failed to load schema: %v", err))
+	}
+
+	result := validateConfig(v.ctx, v.schema, v.checks, configPath, configData)
+	if !result.IsValid {
+		return defaultedConfig{}, result
+	}
+
+	// The config was valid, so parsing and unifying it again cannot fail
+	prepared, _ := prepareConfig(v.ctx, v.checks, configPath, configData)
+	config, _ := parseConfigFile(v.ctx, configPath, prepared.data)
+	unified := v.schema.LookupPath(cue.ParsePath("#Config")).Unify(config)
+
+	return defaultedConfig{config: config, unified: unified, defaults: collectDefaults(unified)}, result
+}
+
+// exportConfig unifies a config file with #Config, fills in the defaults of
+// optional fields and encodes the result in format. Nothing is encoded when
+// the config is not valid; the returned result holds the errors instead.
+func exportConfig(v *validator, configPath string, format string) ([]byte, ValidationResult) {
+	dc, result := loadDefaultedConfig(v, configPath)
+	if !result.IsValid {
+		return nil, result
+	}
+
+	data, err := encodeValue(applyDefaults(dc.unified, dc.defaults), format)
+	if err != nil {
+		return nil, createErrorResult(configPath, CodeValidationFailure, fmt.Sprintf("failed to export: %v", err))
+	}
+	return data, result
+}
+
+// diffConfig returns a unified diff between a config file and the same config
+// with the schema defaults applied, both encoded in the format of the file.
+// The diff is empty when no defaults apply.
+func diffConfig(v *validator, configPath string) (string, ValidationResult) {
+	dc, result := loadDefaultedConfig(v, configPath)
+	if !result.IsValid {
+		return "", result
+	}
+
+	format := "yaml"
+	if ext := configExtension(configPath); ext == ".json" || ext == ".jsonc" {
+		format = "json"
+	}
+
+	before, err := encodeValue(dc.config, format)
+	if err == nil {
+		var after []byte
+		after, err = encodeValue(appendDefaults(dc.config, dc.defaults), format)
+		if err == nil {
+			return unifiedDiff(configPath+" (normalized)", configPath+" (with defaults)", splitLines(before), splitLines(after), 3), result
+		}
+	}
+	return "", createErrorResult(configPath, CodeValidationFailure, fmt.Sprintf("failed to diff: %v", err))
+}
+
+// appendDefaults adds the default fields to the syntax of config, after the
+// fields of their struct. Unlike FillPath, this keeps the order of the fields
+// that are already set.
+func appendDefaults(config cue.Value, defaults []defaultField) cue.Value {
+	expr, ok := config.Syntax(cue.Final()).(ast.Expr)
+	if !ok {
+		return applyDefaults(config, defaults)
+	}
+	for _, d := range defaults {
+		if value, ok := d.value.Syntax(cue.Final()).(ast.Expr); ok {
+			expr = insertField(expr, d.path, value)
+		}
+	}
+	return config.Context().BuildExpr(expr)
+}
+
+// insertField sets the field at path below expr to value, creating the structs
+// on the way that do not exist yet
+func insertField(expr ast.Expr, path []cue.Selector, value ast.Expr) ast.Expr {
+	if len(path) == 0 {
+		return value
+	}
+
+	switch x := expr.(type) {
+	case *ast.ListLit:
+		if path[0].LabelType() == cue.IndexLabel && path[0].Index() < len(x.Elts) {
+			x.Elts[path[0].Index()] = insertField(x.Elts[path[0].Index()], path[1:], value)
+		}
+		return x
+	case *ast.StructLit:
+		name := path[0].Unquoted()
+		for _, elt := range x.Elts {
+			if field, ok := elt.(*ast.Field); ok {
+				if label, _, err := ast.LabelName(field.Label); err == nil && label == name {
+					field.Value = insertField(field.Value, path[1:], value)
+					return x
+				}
+			}
+		}
+		x.Elts = append(x.Elts, &ast.Field{Label: ast.NewString(name), Value: insertField(nil, path[1:], value)})
+		return x
+	default:
+		return insertField(&ast.StructLit{}, path, value)
+	}
+}
+
+// collectDefaults returns the fields of v that are not set and get their value
+// from a default, including optional fields of the structs present in v
+func collectDefaults(v cue.Value) []defaultField {
+	var defaults []defaultField
+
+	addOptional := func(path []cue.Selector, s cue.Value) {
+		iter, err := s.Fields(cue.Optional(true))
+		if err != nil {
+			return
+		}
+		for iter.Next() {
+			if !iter.IsOptional() {
+				continue
+			}
+			// Skip concrete values such as open lists, which default to an empty list
+			field := iter.Value()
+			if value, ok := field.Default(); ok && !field.IsConcrete() {
+				fieldPath := append(path[:len(path):len(path)], cue.Str(iter.Selector().Unquoted()))
+				defaults = append(defaults, defaultField{path: fieldPath, value: value})
+			}
+		}
+	}
+
+	addOptional(nil, v)
+	walkFields(v, func(path []cue.Selector, field cue.Value) bool {
+		if value, ok := field.Default(); ok && !field.IsConcrete() {
+			defaults = append(defaults, defaultField{path: path, value: value})
+			return false
+		}
+		if field.IncompleteKind() == cue.StructKind {
+			addOptional(path, field)
+		}
+		return true
+	})
+
+	return defaults
+}
+
+// applyDefaults sets the default fields in v
+func applyDefaults(v cue.Value, defaults []defaultField) cue.Value {
+	for _, d := range defaults {
+		v = v.FillPath(cue.MakePath(d.path...), d.value)
+	}
+	return v
+}
+
+// splitLines splits encoded data into lines without the final line break
+func splitLines(data []byte) []string {
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// encodeValue encodes a concrete value as YAML or indented JSON
+func encodeValue(v cue.Value, format string) ([]byte, error) {
+	switch format {
+	case "yaml":
+		return yaml.Encode(v)
+	case "json":
+		data, err := v.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, data, "", "  "); err != nil {
+			return nil, err
+		}
+		out.WriteByte('\n')
+		return out.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q (supported: %s)", format, strings.Join(exportFormats, ", "))
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportConfig(t *testing.T) {
+	schema := `
+		#Config: {
+			name: string
+			replicas: int | *1
+			ports?: [...{port: int, protocol?: "TCP" | "UDP" | *"TCP"}]
+			healthCheck?: {
+				path?: string | *"/health"
+				interval?: string | *"30s"
+			}
+		}
+	`
+
+	tests := []struct {
+		name      string
+		content   string
+		format    string
+		want      string
+		wantValid bool
+	}{
+		{
+			name:      "defaults in YAML",
+			content:   "name: web\nhealthCheck:\n  path: /ready\nports:\n  - port: 80\n",
+			format:    "yaml",
+			want:      "name: web\nreplicas: 1\nhealthCheck:\n  path: /ready\n  interval: 30s\nports:\n  - port: 80\n    protocol: TCP\n",
+			wantValid: true,
+		},
+		{
+			name:      "optional struct not set",
+			content:   "name: web\nreplicas: 2\n",
+			format:    "json",
+			want:      "{\n  \"name\": \"web\",\n  \"replicas\": 2\n}\n",
+			wantValid: true,
+		},
+		{
+			name:      "invalid config",
+			content:   "name: 1\n",
+			format:    "yaml",
+			wantValid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			schemaPath := filepath.Join(tmpDir, "schema.cue")
+			if err := os.WriteFile(schemaPath, []byte(schema), 0644); err != nil {
+				t.Fatalf("failed to write schema file: %v", err)
+			}
+
+			configPath := filepath.Join(tmpDir, "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			data, result := exportConfig(newValidator(schemaPath, checkOptions{}, nil), configPath, tt.format)
+
+			if result.IsValid != tt.wantValid {
+				t.Fatalf("IsValid = %v, want %v (errors: %v)", result.IsValid, tt.wantValid, result.Errors)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	schemaPath := filepath.Join(tmpDir, "schema.cue")
+	schema := `#Config: {name: string, replicas: int | *1, healthCheck?: {path?: string | *"/health", port: int}}`
+	if err := os.WriteFile(schemaPath, []byte(schema), 0644); err != nil {
+		t.Fatalf("failed to write schema file: %v", err)
+	}
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("healthCheck:\n  port: 8080\nname: web\n"), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	diff, result := diffConfig(newValidator(schemaPath, checkOptions{}, nil), configPath)
+	if !result.IsValid {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	want := "--- " + configPath + " (normalized)\n+++ " + configPath + " (with defaults)\n" +
+		"@@ -1,3 +1,5 @@\n healthCheck:\n   port: 8080\n+  path: /health\n name: web\n+replicas: 1\n"
+	if diff != want {
+		t.Errorf("got:\n%s\nwant:\n%s", diff, want)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// supportedExtensions lists the config file extensions cint can validate
+var supportedExtensions = []string{".yaml", ".yml", ".json", ".jsonc"}
+
+// isSupportedFile checks if a file has a supported config file extension
+func isSupportedFile(path string) bool {
+	return slices.Contains(supportedExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// isConfigFile checks if a file is validated when it is found below a --config
+// directory or in git, which includes templates only when they are enabled
+func isConfigFile(path string, templates bool) bool {
+	return isSupportedFile(path) || templates && isTemplateFile(path)
+}
+
+// collectConfigPaths resolves the --config arguments into the files to validate.
+// In git mode the arguments are patterns that filter the changed files.
+// Templates are included when values to render them are given.
+func collectConfigPaths(opts cliOptions) ([]string, error) {
+	ignore := newIgnoreMatcher(opts.gitignore)
+	templates := len(opts.values) > 0
+
+	if opts.gitMode() {
+		return collectChangedConfigPaths(opts, ignore, templates)
+	}
+
+	var paths []string
+	for _, pattern := range opts.configPaths {
+		expanded, err := expandConfigPattern(pattern, ignore, templates)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, expanded...)
+	}
+	return paths, nil
+}
+
+// expandConfigPattern expands a directory or glob pattern into config files that
+// are not ignored. Plain file paths are returned as they are, even when ignored,
+// so that missing files are reported by validation.
+func expandConfigPattern(pattern string, ignore *ignoreMatcher, templates bool) ([]string, error) {
+	if !hasGlobMeta(pattern) {
+		if isDir(pattern) {
+			return walkConfigDir(pattern, ignore, templates)
+		}
+		return []string{pattern}, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --config pattern %q: %w", pattern, err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("--config pattern %q matched no files", pattern)
+	}
+
+	var paths []string
+	for _, match := range matches {
+		if isDir(match) {
+			walked, err := walkConfigDir(match, ignore, templates)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, walked...)
+			continue
+		}
+		if !ignore.isIgnored(match, false) {
+			paths = append(paths, match)
+		}
+	}
+	return paths, nil
+}
+
+// walkConfigDir returns all config files below root that are not ignored
+func walkConfigDir(root string, ignore *ignoreMatcher, templates bool) ([]string, error) {
+	var paths []string
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != root && (d.Name() == ".git" || ignore.matches(path, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if isConfigFile(path, templates) && !ignore.matches(path, false) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking %s: %w", root, err)
+	}
+
+	return paths, nil
+}
+
+// collectChangedConfigPaths returns the changed files reported by git that match
+// the --config patterns, or all changed config files when no pattern is given
+func collectChangedConfigPaths(opts cliOptions, ignore *ignoreMatcher, templates bool) ([]string, error) {
+	changed, err := gitChangedFiles(opts.changedSince, opts.staged)
+	if err != nil {
+		return nil, err
+	}
+	return selectGitConfigPaths(changed, opts, ignore, templates), nil
+}
+
+// collectContextConfigPaths returns the config files that git mode does not
+// validate, because they did not change, but that the cross-file checks need
+// to see the whole repository. It returns nil outside of git mode, where the
+// cross-file checks see every config of the run.
+func collectContextConfigPaths(opts cliOptions, configPaths []string) ([]string, error) {
+	if !opts.gitMode() {
+		return nil, nil
+	}
+	tracked, err := gitTrackedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, path := range selectGitConfigPaths(tracked, opts, newIgnoreMatcher(opts.gitignore), len(opts.values) > 0) {
+		if !slices.Contains(configPaths, path) && !slices.Contains(opts.merge, path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// selectGitConfigPaths returns the config files among the paths reported by
+// git that are not ignored and are selected by the --config patterns, if any
+func selectGitConfigPaths(gitPaths []string, opts cliOptions, ignore *ignoreMatcher, templates bool) []string {
+	var paths []string
+	for _, path := range gitPaths {
+		if !isConfigFile(path, templates) || ignore.isIgnored(path, false) {
+			continue
+		}
+		if len(opts.configPaths) > 0 && !matchesAnyConfigPattern(path, opts.configPaths) {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// matchesAnyConfigPattern checks if path is selected by any of the --config patterns
+func matchesAnyConfigPattern(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matchesConfigPattern(path, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesConfigPattern checks if path equals pattern, lies below the directory
+// pattern or matches the glob pattern
+func matchesConfigPattern(path string, pattern string) bool {
+	path = filepath.Clean(path)
+	pattern = filepath.Clean(pattern)
+
+	if path == pattern || pattern == "." {
+		return true
+	}
+	if strings.HasPrefix(path, pattern+string(filepath.Separator)) {
+		return true
+	}
+	matched, _ := filepath.Match(pattern, path)
+	return matched
+}
+
+// isDir checks if path is an existing directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// hasGlobMeta checks if a pattern contains glob metacharacters
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[`)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchesConfigPattern(t *testing.T) {
+	tests := []struct {
+		path    string
+		pattern string
+		want    bool
+	}{
+		{"service.yaml", "service.yaml", true},
+		{"service.yaml", "./service.yaml", true},
+		{"configs/service.yaml", "configs", true},
+		{"configs/service.yaml", "configs/", true},
+		{"configs/nested/service.yaml", "configs", true},
+		{"configs-old/service.yaml", "configs", false},
+		{"configs/service.yaml", "configs/*.yaml", true},
+		{"configs/nested/service.yaml", "configs/*.yaml", false},
+		{"configs/service.json", "configs/*.yaml", false},
+		{"service.yaml", ".", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.pattern, func(t *testing.T) {
+			got := matchesConfigPattern(filepath.FromSlash(tt.path), filepath.FromSlash(tt.pattern))
+			if got != tt.want {
+				t.Errorf("matchesConfigPattern(%q, %q) = %v, want %v", tt.path, tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
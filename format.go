@@ -18,30 +18,51 @@ func FormatResults(results []ValidationResult) string {
 
 // formatSingleResult formats a single validation result
 func formatSingleResult(output *strings.Builder, result ValidationResult) {
-	if result.IsValid {
+	switch {
+	case result.IsValid && len(result.Errors) == 0 && result.OmittedErrors == 0:
 		fmt.Fprintf(output, "%s: ok\n", result.FileName)
 		return
+	case result.IsValid:
+		fmt.Fprintf(output, "WARN: %s\n", result.FileName)
+	default:
+		fmt.Fprintf(output, "FAIL: %s\n", result.FileName)
 	}
 
-	fmt.Fprintf(output, "FAIL: %s\n", result.FileName)
 	for _, err := range result.Errors {
 		formatError(output, err)
 	}
+	if result.OmittedErrors > 0 {
+		fmt.Fprintf(output, "  ... %d more errors not shown\n", result.OmittedErrors)
+	}
 }
 
 // formatError formats a single validation error
 func formatError(output *strings.Builder, err ValidationError) {
-	switch {
-	case err.Line > 0 && err.Field != "":
-		fmt.Fprintf(output, "  line %d, field \"%s\": %s\n",
-			err.Line, err.Field, err.Problem)
-	case err.Line > 0:
-		fmt.Fprintf(output, "  line %d: %s\n",
-			err.Line, err.Problem)
-	case err.Field != "":
-		fmt.Fprintf(output, "  field \"%s\": %s\n",
-			err.Field, err.Problem)
-	default:
-		fmt.Fprintf(output, "  %s\n", err.Problem)
+	output.WriteString("  ")
+	if err.IsWarning() {
+		output.WriteString("warning: ")
+	}
+
+	var location []string
+	if err.File != "" {
+		location = append(location, err.File)
+	}
+	if err.Line > 0 {
+		location = append(location, fmt.Sprintf("line %d", err.Line))
+	}
+	if err.Field != "" {
+		location = append(location, fmt.Sprintf("field \"%s\"", err.Field))
+	}
+	if len(location) > 0 {
+		output.WriteString(strings.Join(location, ", ") + ": ")
+	}
+	output.WriteString(err.Problem)
+	if err.Code != "" {
+		fmt.Fprintf(output, " [%s]", err.Code)
+	}
+	output.WriteString("\n")
+
+	if err.Detail != "" {
+		fmt.Fprintf(output, "    %s\n", err.Detail)
 	}
 }
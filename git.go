@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitChangedFiles returns the files that changed relative to ref, or the staged
+// files when staged is set. Paths are relative to the current directory and
+// limited to it. Deleted files are left out and renamed files are reported
+// under their new name.
+func gitChangedFiles(ref string, staged bool) ([]string, error) {
+	args := []string{"diff", "--name-only", "-z", "--diff-filter=ACMR", "--find-renames", "--relative"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	if ref != "" {
+		args = append(args, ref)
+	}
+	args = append(args, "--")
+
+	cmd := exec.Command("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git diff: %s", msg)
+		}
+		return nil, fmt.Errorf("git diff: %w", err)
+	}
+
+	return splitGitPaths(out), nil
+}
+
+// gitTrackedFiles returns the files tracked in the git index. Paths are relative
+// to the current directory and limited to it.
+func gitTrackedFiles() ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "-z")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git ls-files: %s", msg)
+		}
+		return nil, fmt.Errorf("git ls-files: %w", err)
+	}
+
+	return splitGitPaths(out), nil
+}
+
+// readStagedFile reads a file as staged in the git index, which is what the
+// next commit will contain. Relative paths are relative to the current directory.
+func readStagedFile(path string) ([]byte, error) {
+	if filepath.IsAbs(path) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		if path, err = filepath.Rel(wd, path); err != nil {
+			return nil, err
+		}
+	}
+	cmd := exec.Command("git", "show", ":./"+filepath.ToSlash(path))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git show: %s", msg)
+		}
+		return nil, fmt.Errorf("git show: %w", err)
+	}
+	return out, nil
+}
+
+// splitGitPaths splits NUL-separated git output into native paths
+func splitGitPaths(out []byte) []string {
+	var paths []string
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p == "" {
+			continue
+		}
+		paths = append(paths, filepath.FromSlash(p))
+	}
+	return paths
+}
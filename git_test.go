@@ -0,0 +1,236 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// initGitRepo creates a git repository in the current directory with files
+// committed and returns a function that runs git commands in it
+func initGitRepo(t *testing.T, files map[string]string) func(args ...string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	writeFiles(t, files)
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	return git
+}
+
+// writeFiles writes files relative to the current directory
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestGitChangedFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	git := initGitRepo(t, map[string]string{
+		"modified.yaml":         "a: 1\n",
+		"deleted.yaml":          "a: 1\n",
+		"old.yaml":              "a: 1\nb: 2\nc: 3\n",
+		"configs/service.yaml":  "a: 1\n",
+		"configs/unstaged.yaml": "a: 1\n",
+	})
+
+	writeFiles(t, map[string]string{
+		"modified.yaml":         "a: 2\n",
+		"added.yaml":            "a: 1\n",
+		"configs/service.yaml":  "a: 2\n",
+		"configs/unstaged.yaml": "a: 2\n",
+	})
+	git("add", "modified.yaml", "added.yaml", "configs/service.yaml")
+	git("rm", "-q", "deleted.yaml")
+	git("mv", "old.yaml", "new.yaml")
+
+	tests := []struct {
+		name   string
+		dir    string
+		ref    string
+		staged bool
+		want   []string
+	}{
+		{
+			name:   "staged",
+			staged: true,
+			want:   []string{"added.yaml", "configs/service.yaml", "modified.yaml", "new.yaml"},
+		},
+		{
+			name: "changed since HEAD",
+			ref:  "HEAD",
+			want: []string{"added.yaml", "configs/service.yaml", "configs/unstaged.yaml", "modified.yaml", "new.yaml"},
+		},
+		{
+			name:   "staged in subdirectory",
+			dir:    "configs",
+			staged: true,
+			want:   []string{"service.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.dir != "" {
+				t.Chdir(tt.dir)
+			}
+
+			got, err := gitChangedFiles(tt.ref, tt.staged)
+			if err != nil {
+				t.Fatalf("gitChangedFiles failed: %v", err)
+			}
+			want := make([]string, len(tt.want))
+			for i, path := range tt.want {
+				want[i] = filepath.FromSlash(path)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithStaged(t *testing.T) {
+	t.Chdir(t.TempDir())
+	git := initGitRepo(t, map[string]string{
+		"schema.cue":  "#Config: {replicas: int & <=5}\n",
+		"config.yaml": "replicas: 1\n",
+	})
+
+	// The staged content is invalid, while the working tree has been fixed
+	writeFiles(t, map[string]string{"config.yaml": "replicas: 9\n"})
+	git("add", "config.yaml")
+	writeFiles(t, map[string]string{"config.yaml": "replicas: 2\n"})
+
+	if results := ValidateFiles("schema.cue", []string{"config.yaml"}); !results[0].IsValid {
+		t.Errorf("working tree: expected a valid config, got %v", results[0].Errors)
+	}
+
+	opts := DefaultOptions()
+	opts.Staged = true
+	results := ValidateFilesWithOptions("schema.cue", []string{"config.yaml"}, opts)
+	if results[0].IsValid {
+		t.Error("index: expected the staged config to fail")
+	}
+}
+
+func TestCollectionWithStaged(t *testing.T) {
+	t.Chdir(t.TempDir())
+	git := initGitRepo(t, map[string]string{
+		"schema.cue": `
+			#Config: {name: string, port: int}
+			#Collection: X={
+				[string]: #Config
+				_names: {for file, c in X {(c.name): file}}
+			}
+		`,
+		"web.yaml":    "name: web\nport: 80\n",
+		"api.yaml":    "name: api\nport: 8080\n",
+		"broken.yaml": "name: api\nport: http\n",
+	})
+
+	// The unchanged web.yaml already uses the name
+	writeFiles(t, map[string]string{"api.yaml": "name: web\nport: 8080\n"})
+	git("add", "api.yaml")
+
+	cli := cliOptions{schemaPath: "schema.cue", staged: true}
+	configPaths, err := collectConfigPaths(cli)
+	if err != nil {
+		t.Fatalf("collectConfigPaths failed: %v", err)
+	}
+	contextPaths, err := collectContextConfigPaths(cli, configPaths)
+	if err != nil {
+		t.Fatalf("collectContextConfigPaths failed: %v", err)
+	}
+	if !slices.Equal(configPaths, []string{"api.yaml"}) || !slices.Equal(contextPaths, []string{"broken.yaml", "web.yaml"}) {
+		t.Fatalf("configPaths = %v, contextPaths = %v", configPaths, contextPaths)
+	}
+
+	opts := DefaultOptions()
+	opts.Staged = true
+	opts.Context = contextPaths
+	results := ValidateFilesWithOptions("schema.cue", configPaths, opts)
+
+	last := results[len(results)-1]
+	if last.FileName != "#Collection" || last.IsValid {
+		t.Fatalf("expected a failing #Collection result, got %+v", last)
+	}
+	if len(last.Errors) != 1 || !strings.Contains(last.Errors[0].Field, "_names.web") {
+		t.Errorf("expected a single conflict on _names.web, got %v", last.Errors)
+	}
+}
+
+func TestReferencesWithStaged(t *testing.T) {
+	t.Chdir(t.TempDir())
+	git := initGitRepo(t, map[string]string{
+		"s.cue": `
+			#Config: {
+				team?: string @cint(ref="teams.name")
+				teams?: [...{name: string}]
+			}
+		`,
+		"teams.yaml": "teams:\n  - name: platform\n",
+		"web.yaml":   "team: platform\n",
+	})
+
+	// Only web.yaml is staged, while teams.yaml defines the team
+	tests := []struct {
+		name      string
+		web       string
+		wantValid bool
+	}{
+		{name: "defined in unchanged file", web: "# owned by platform\nteam: platform\n", wantValid: true},
+		{name: "dangling", web: "team: platfrom\n", wantValid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeFiles(t, map[string]string{"web.yaml": tt.web})
+			git("add", "web.yaml")
+
+			cli := cliOptions{schemaPath: "s.cue", staged: true}
+			configPaths, err := collectConfigPaths(cli)
+			if err != nil {
+				t.Fatalf("collectConfigPaths failed: %v", err)
+			}
+			contextPaths, err := collectContextConfigPaths(cli, configPaths)
+			if err != nil {
+				t.Fatalf("collectContextConfigPaths failed: %v", err)
+			}
+
+			opts := DefaultOptions()
+			opts.Staged = true
+			opts.Context = contextPaths
+			results := ValidateFilesWithOptions("s.cue", configPaths, opts)
+
+			last := results[len(results)-1]
+			if last.FileName != "#Collection" || last.IsValid != tt.wantValid {
+				t.Fatalf("#Collection result = %+v, want valid: %v", last, tt.wantValid)
+			}
+			if !tt.wantValid && (last.Errors[0].File != "web.yaml" || last.Errors[0].Code != CodeDanglingReference) {
+				t.Errorf("expected a dangling reference in web.yaml, got %v", last.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// cintIgnoreFile is the name of the ignore file that cint always honors
+const cintIgnoreFile = ".cintignore"
+
+// ignoreMatcher decides which files are skipped based on ignore files using
+// gitignore syntax. Ignore files are read lazily from every directory that is
+// an ancestor of a checked path up to the repository root, and rules in deeper
+// directories take precedence.
+type ignoreMatcher struct {
+	fileNames []string
+	root      string                  // Outermost directory searched for ignore files
+	rules     map[string][]ignoreRule // Rules by the absolute directory of their ignore file
+}
+
+// ignoreRule is a single compiled line of an ignore file
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// newIgnoreMatcher creates a matcher honoring .cintignore and optionally .gitignore
+func newIgnoreMatcher(useGitignore bool) *ignoreMatcher {
+	fileNames := []string{cintIgnoreFile}
+	if useGitignore {
+		fileNames = append(fileNames, ".gitignore")
+	}
+	return &ignoreMatcher{
+		fileNames: fileNames,
+		root:      repositoryRoot(),
+		rules:     make(map[string][]ignoreRule),
+	}
+}
+
+// repositoryRoot returns the closest directory containing .git, starting from
+// the current directory, or the current directory when it is not in a repository
+func repositoryRoot() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for dir := wd; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		if dir == filepath.Dir(dir) {
+			return wd
+		}
+	}
+}
+
+// isIgnored checks if a path or any of its parent directories is ignored
+func (m *ignoreMatcher) isIgnored(path string, isDir bool) bool {
+	path = absolutePath(path)
+
+	parents := ancestorDirs(path, m.root)
+	for i := len(parents) - 1; i >= 1; i-- {
+		if m.matches(parents[i-1], true) {
+			return true
+		}
+	}
+	return m.matches(path, isDir)
+}
+
+// matches applies the rules of all ancestor directories to a single path without
+// looking at its parents. The last matching rule wins.
+func (m *ignoreMatcher) matches(path string, isDir bool) bool {
+	path = absolutePath(path)
+	ignored := false
+
+	dirs := ancestorDirs(path, m.root)
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir := dirs[i]
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+
+		for _, rule := range m.rulesFor(dir) {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.re.MatchString(rel) {
+				ignored = !rule.negate
+			}
+		}
+	}
+
+	return ignored
+}
+
+// rulesFor returns the rules from the ignore files in dir, reading them on first use
+func (m *ignoreMatcher) rulesFor(dir string) []ignoreRule {
+	if rules, ok := m.rules[dir]; ok {
+		return rules
+	}
+
+	var rules []ignoreRule
+	for _, name := range m.fileNames {
+		rules = append(rules, readIgnoreFile(filepath.Join(dir, name))...)
+	}
+	m.rules[dir] = rules
+	return rules
+}
+
+// ancestorDirs returns the directories containing the absolute path up to root,
+// innermost first. Paths outside of root have all their ancestors.
+func ancestorDirs(path string, root string) []string {
+	var dirs []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == root || dir == filepath.Dir(dir) {
+			return dirs
+		}
+	}
+}
+
+// absolutePath returns the cleaned absolute form of path, or the cleaned path
+// when the current directory is unknown
+func absolutePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// readIgnoreFile reads the rules of an ignore file. A missing file has no rules.
+func readIgnoreFile(path string) []ignoreRule {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// parseIgnoreRule compiles a single line of an ignore file using gitignore syntax
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = trimIgnoreLine(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// A pattern with a slash is relative to the ignore file, otherwise it
+	// matches a name at any depth
+	prefix := "^(?:.*/)?"
+	if strings.Contains(line, "/") {
+		prefix = "^"
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	re, err := regexp.Compile(prefix + globToRegexp(line) + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// trimIgnoreLine removes trailing spaces unless they are escaped with a backslash
+func trimIgnoreLine(line string) string {
+	trimmed := strings.TrimRight(line, " \t\r")
+	if strings.HasSuffix(trimmed, `\`) && len(trimmed) < len(line) {
+		trimmed += " "
+	}
+	return trimmed
+}
+
+// globToRegexp converts a gitignore glob to a regular expression
+func globToRegexp(glob string) string {
+	var re strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**" && i > 0 && glob[i-1] == '/':
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			re.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return re.String()
+}
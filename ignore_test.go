@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseIgnoreRule(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"node_modules", "node_modules", true, true},
+		{"node_modules", "web/node_modules", true, true},
+		{"*.json", "configs/service.json", false, true},
+		{"*.json", "configs/service.yaml", false, false},
+		{"/generated", "generated", true, true},
+		{"/generated", "configs/generated", true, false},
+		{"charts/vendor", "charts/vendor", true, true},
+		{"charts/vendor", "x/charts/vendor", true, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"**/fixtures", "a/b/fixtures", true, true},
+		{"**/fixtures", "fixtures", true, true},
+		{"a/**/b.yaml", "a/b.yaml", false, true},
+		{"a/**/b.yaml", "a/x/y/b.yaml", false, true},
+		{"testdata/**", "testdata/x/y.yaml", false, true},
+		{"testdata/**", "testdata", true, false},
+		{"service-?.yaml", "service-a.yaml", false, true},
+		{"service-[ab].yaml", "service-b.yaml", false, true},
+		{"service-[!ab].yaml", "service-b.yaml", false, false},
+		{`\#notes.yaml`, "#notes.yaml", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			rule, ok := parseIgnoreRule(tt.pattern)
+			if !ok {
+				t.Fatalf("parseIgnoreRule(%q) returned no rule", tt.pattern)
+			}
+
+			got := rule.re.MatchString(tt.path) && (!rule.dirOnly || tt.isDir)
+			if got != tt.want {
+				t.Errorf("pattern %q on %q (dir=%v) = %v, want %v", tt.pattern, tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkConfigDirWithIgnoreFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		".cintignore":                     "vendor/\n*.generated.yaml\n",
+		".gitignore":                      "node_modules/\n",
+		"service.yaml":                    "",
+		"service.generated.yaml":          "",
+		"notes.txt":                       "",
+		"vendor/chart.yaml":               "",
+		"node_modules/pkg/package.json":   "",
+		"team/.cintignore":                "!keep.generated.yaml\n",
+		"team/keep.generated.yaml":        "",
+		"team/other.generated.yaml":       "",
+		"team/settings.json":              "",
+		".git/config.yaml":                "",
+		"team/nested/deep/service.yml":    "",
+		"team/nested/deep/.cintignore":    "*.yml\n",
+		"team/nested/deep/service.json":   "",
+		"team/nested/deep/vendor/x.yaml":  "",
+		"team/nested/deep/other/app.yaml": "",
+		"team/nested/deep/other/app.toml": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		gitignore bool
+		want      []string
+	}{
+		{
+			name: "cintignore only",
+			want: []string{
+				"node_modules/pkg/package.json",
+				"service.yaml",
+				"team/keep.generated.yaml",
+				"team/nested/deep/other/app.yaml",
+				"team/nested/deep/service.json",
+				"team/settings.json",
+			},
+		},
+		{
+			name:      "with gitignore",
+			gitignore: true,
+			want: []string{
+				"service.yaml",
+				"team/keep.generated.yaml",
+				"team/nested/deep/other/app.yaml",
+				"team/nested/deep/service.json",
+				"team/settings.json",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := walkConfigDir(tmpDir, newIgnoreMatcher(tt.gitignore), false)
+			if err != nil {
+				t.Fatalf("walkConfigDir failed: %v", err)
+			}
+
+			var got []string
+			for _, path := range paths {
+				rel, _ := filepath.Rel(tmpDir, path)
+				got = append(got, filepath.ToSlash(rel))
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got files %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("file %d = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWalkConfigDirFromSubdirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		".git/HEAD":                  "",
+		".cintignore":                "vendor/\n",
+		"configs/app.yaml":           "",
+		"configs/vendor/chart.yaml":  "",
+		"configs/team/.cintignore":   "!vendor/\n",
+		"configs/team/vendor/x.yaml": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	t.Chdir(filepath.Join(tmpDir, "configs"))
+
+	paths, err := walkConfigDir(".", newIgnoreMatcher(false), false)
+	if err != nil {
+		t.Fatalf("walkConfigDir failed: %v", err)
+	}
+
+	want := []string{"app.yaml", filepath.Join("team", "vendor", "x.yaml")}
+	if len(paths) != len(want) {
+		t.Fatalf("got files %v, want %v", paths, want)
+	}
+	for i := range paths {
+		if paths[i] != want[i] {
+			t.Errorf("file %d = %s, want %s", i, paths[i], want[i])
+		}
+	}
+}
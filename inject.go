@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/load"
+)
+
+// parseAssignment splits a name=value option argument
+func parseAssignment(option string, s string) (string, string, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" || value == "" {
+		return "", "", fmt.Errorf("invalid %s %q (expected name=value)", option, s)
+	}
+	return name, value, nil
+}
+
+// validateInjections checks the arguments of --data and --tag
+func validateInjections(data []string, tags []string) error {
+	for _, d := range data {
+		if _, _, err := parseAssignment("--data", d); err != nil {
+			return err
+		}
+	}
+	for _, tag := range tags {
+		if _, _, err := parseAssignment("--tag", tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildSchemaWithTags loads the schema file with the CUE loader, which is the
+// only way to set the values of @tag() attributes
+func buildSchemaWithTags(ctx *cue.Context, schemaPath string, tags []string) (cue.Value, error) {
+	instances := load.Instances([]string{schemaPath}, &load.Config{Tags: tags})
+	if err := instances[0].Err; err != nil {
+		return cue.Value{}, fmt.Errorf("loading schema: %w", err)
+	}
+
+	schema := ctx.BuildInstance(instances[0])
+	if schema.Err() != nil {
+		return cue.Value{}, fmt.Errorf("compiling schema: %w", schema.Err())
+	}
+	return schema, nil
+}
+
+// injectData fills each name=path data file into the top-level schema field
+// name. The field must be declared by the schema, e.g. as data: _, so that the
+// schema compiles without the data.
+func injectData(ctx *cue.Context, schema cue.Value, data []string) (cue.Value, error) {
+	for _, d := range data {
+		name, dataPath, err := parseAssignment("--data", d)
+		if err != nil {
+			return cue.Value{}, err
+		}
+
+		value, err := readDataFile(ctx, dataPath)
+		if err != nil {
+			return cue.Value{}, err
+		}
+
+		path := cue.MakePath(cue.Str(name))
+		if !schema.LookupPath(path).Exists() {
+			return cue.Value{}, fmt.Errorf("schema does not declare data field %q", name)
+		}
+		schema = schema.FillPath(path, value)
+		if err := schema.LookupPath(path).Validate(); err != nil {
+			return cue.Value{}, fmt.Errorf("data file %s does not match field %q: %w", dataPath, name, err)
+		}
+	}
+	return schema, nil
+}
+
+// readDataFile reads and parses a YAML or JSON file that provides values to
+// the schema or to templates
+func readDataFile(ctx *cue.Context, dataPath string) (cue.Value, error) {
+	dataBytes, err := os.ReadFile(dataPath)
+	if err != nil {
+		return cue.Value{}, fmt.Errorf("reading data file: %w", err)
+	}
+	value, err := parseConfigFile(ctx, dataPath, dataBytes)
+	if err != nil {
+		return cue.Value{}, fmt.Errorf("parsing data file %s: %w", dataPath, err)
+	}
+	if value.Err() != nil {
+		return cue.Value{}, fmt.Errorf("parsing data file %s: %w", dataPath, value.Err())
+	}
+	return value, nil
+}
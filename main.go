@@ -5,39 +5,99 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 )
 
 const version = "0.1.0"
 
+// cliOptions holds the parsed command-line options
+type cliOptions struct {
+	schemaPath  string
+	configPaths stringSlice
+	merge       stringSlice
+	mergeMode   string
+	jobs        int
+	failFast    bool
+	maxErrors   int
+	format      string
+	concrete    string
+	closed      string
+	env         string
+	data        stringSlice
+	tags        stringSlice
+	values      stringSlice
+	cache       bool
+	cacheDir    string
+
+	changedSince string
+	staged       bool
+	gitignore    bool
+
+	baseline      string
+	writeBaseline string
+
+	showVersion bool
+}
+
 func main() {
-	var (
-		schemaPath  string
-		configPaths stringSlice
-		showVersion bool
-	)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			runExport(os.Args[2:])
+		case "diff":
+			runDiff(os.Args[2:])
+		}
+	}
+
+	var opts cliOptions
 
-	setupFlags(&schemaPath, &configPaths, &showVersion)
+	setupFlags(&opts)
 	flag.Parse()
 
-	if showVersion {
+	if opts.showVersion {
 		printVersion()
 		os.Exit(0)
 	}
 
-	if err := validateArgs(schemaPath, configPaths); err != nil {
+	if err := validateArgs(opts); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	runValidation(schemaPath, configPaths)
+	runValidation(opts)
+}
+
+// gitMode reports whether the files to validate are selected from git
+func (o cliOptions) gitMode() bool {
+	return o.changedSince != "" || o.staged
 }
 
 // setupFlags configures command-line flags
-func setupFlags(schemaPath *string, configPaths *stringSlice, showVersion *bool) {
-	flag.StringVar(schemaPath, "schema", "", "Path to CUE schema file (required)")
-	flag.Var(configPaths, "config", "Path to config file to validate (can be specified multiple times)")
-	flag.BoolVar(showVersion, "version", false, "Show version")
+func setupFlags(opts *cliOptions) {
+	flag.StringVar(&opts.schemaPath, "schema", "", "Path to CUE schema file (required)")
+	flag.Var(&opts.configPaths, "config", "Path, directory or glob pattern of config files to validate (can be specified multiple times)")
+	flag.Var(&opts.merge, "merge", "Config file merged with the other --merge files and validated as a single config (can be specified multiple times)")
+	flag.StringVar(&opts.mergeMode, "merge-mode", "unify", "How --merge files are combined (unify: setting a field to different values is a conflict, override: later files override earlier ones)")
+	flag.IntVar(&opts.jobs, "jobs", 1, "Number of files to validate in parallel (0 means number of CPUs)")
+	flag.BoolVar(&opts.failFast, "fail-fast", false, "Stop after the first file that fails validation")
+	flag.IntVar(&opts.maxErrors, "max-errors", 0, "Stop after reporting N errors in total (0 means unlimited)")
+	flag.StringVar(&opts.format, "format", "text", "Output format (text, jsonl)")
+	flag.StringVar(&opts.concrete, "concreteness", "default", "How complete configs must be (lenient: allow missing fields, default: require fields or defaults, strict: also flag fields relying on defaults)")
+	flag.StringVar(&opts.closed, "closedness", "default", "How fields the schema does not declare are reported (open: as warnings, default: as written in the schema, closed: also in structs opened with ...)")
+	flag.StringVar(&opts.env, "env", "off", "How ${VAR} placeholders in configs are handled (off: as plain strings, expand: replace with environment variables, placeholder: accept for any scalar field)")
+	flag.Var(&opts.data, "data", "Data file filled into a top-level schema field, as name=path.yaml (can be specified multiple times)")
+	flag.Var(&opts.tags, "tag", "Value of a CUE @tag() attribute in the schema, as key=value (can be specified multiple times)")
+	flag.Var(&opts.values, "values", "Values file used to render *.tmpl configs, merged in order (can be specified multiple times, enables templates below --config directories)")
+	flag.BoolVar(&opts.cache, "cache", false, "Skip files whose results are cached from a previous run")
+	flag.StringVar(&opts.cacheDir, "cache-dir", "", "Directory for cached results (implies --cache, default: user cache directory)")
+	flag.StringVar(&opts.changedSince, "changed-since", "", "Only validate config files changed in git since this ref")
+	flag.BoolVar(&opts.staged, "staged", false, "Only validate config files staged in git, reading their staged content")
+	flag.BoolVar(&opts.gitignore, "gitignore", false, "Also skip files ignored by .gitignore files")
+	flag.StringVar(&opts.baseline, "baseline", "", "Only report errors that are not recorded in this baseline file")
+	flag.StringVar(&opts.writeBaseline, "write-baseline", "", "Record all current errors in this baseline file")
+	flag.BoolVar(&opts.showVersion, "version", false, "Show version")
 	flag.Usage = createUsageFunc()
 }
 
@@ -46,7 +106,9 @@ func createUsageFunc() func() {
 	return func() {
 		progName := filepath.Base(os.Args[0])
 		fmt.Fprintf(os.Stderr, "cint - Configuration linter powered by CUE\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: %s --schema=<schema.cue> --config=<config.yaml> [--config=<config2.yaml>...]\n\n", progName)
+		fmt.Fprintf(os.Stderr, "Usage: %s --schema=<schema.cue> --config=<config.yaml> [--config=<config2.yaml>...]\n", progName)
+		fmt.Fprintf(os.Stderr, "       %s export --schema=<schema.cue> --config=<config.yaml> [--format=yaml|json]\n", progName)
+		fmt.Fprintf(os.Stderr, "       %s diff --schema=<schema.cue> --config=<config.yaml>\n\n", progName)
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
@@ -54,6 +116,27 @@ func createUsageFunc() func() {
 		fmt.Fprintf(os.Stderr, "  %s --schema=app.cue --config=service.yaml\n\n", progName)
 		fmt.Fprintf(os.Stderr, "  # Validate multiple files\n")
 		fmt.Fprintf(os.Stderr, "  %s --schema=app.cue --config=service-a.yaml --config=service-b.yaml\n\n", progName)
+		fmt.Fprintf(os.Stderr, "  # Validate all config files below a directory\n")
+		fmt.Fprintf(os.Stderr, "  %s --schema=app.cue --config=configs/\n\n", progName)
+		fmt.Fprintf(os.Stderr, "  # Validate staged YAML files below configs/ in a pre-commit hook\n")
+		fmt.Fprintf(os.Stderr, "  %s --schema=app.cue --staged --config='configs/*.yaml'\n\n", progName)
+		fmt.Fprintf(os.Stderr, "  # Record existing errors, then only fail on new ones\n")
+		fmt.Fprintf(os.Stderr, "  %s --schema=app.cue --config=configs/ --write-baseline=cint-baseline.json\n", progName)
+		fmt.Fprintf(os.Stderr, "  %s --schema=app.cue --config=configs/ --baseline=cint-baseline.json\n\n", progName)
+		fmt.Fprintf(os.Stderr, "  # Validate a base file and an environment overlay as a single config\n")
+		fmt.Fprintf(os.Stderr, "  %s --schema=app.cue --merge=base.yaml --merge=prod.yaml\n\n", progName)
+		fmt.Fprintf(os.Stderr, "  # Survey unknown fields across the repository without failing\n")
+		fmt.Fprintf(os.Stderr, "  %s --schema=app.cue --closedness=open --config=configs/\n\n", progName)
+		fmt.Fprintf(os.Stderr, "  # Validate incomplete base files that are merged with overlays later\n")
+		fmt.Fprintf(os.Stderr, "  %s --schema=app.cue --concreteness=lenient --config=base/\n\n", progName)
+		fmt.Fprintf(os.Stderr, "  # Validate configs with the values the deploy system will substitute\n")
+		fmt.Fprintf(os.Stderr, "  %s --schema=app.cue --env=expand --config=configs/\n\n", progName)
+		fmt.Fprintf(os.Stderr, "  # Check team names against a list maintained elsewhere, with production limits\n")
+		fmt.Fprintf(os.Stderr, "  %s --schema=app.cue --data=catalog=teams.yaml --tag=env=prod --config=configs/\n\n", progName)
+		fmt.Fprintf(os.Stderr, "  # Render Go templates with sample values and validate the output\n")
+		fmt.Fprintf(os.Stderr, "  %s --schema=app.cue --values=values.yaml --values=prod.yaml --config=templates/\n\n", progName)
+		fmt.Fprintf(os.Stderr, "  # Validate multiple files using 8 workers\n")
+		fmt.Fprintf(os.Stderr, "  %s --schema=app.cue --jobs=8 --config=service-a.yaml --config=service-b.yaml\n\n", progName)
 	}
 }
 
@@ -63,34 +146,265 @@ func printVersion() {
 }
 
 // validateArgs validates command-line arguments
-func validateArgs(schemaPath string, configPaths []string) error {
-	if schemaPath == "" {
+func validateArgs(opts cliOptions) error {
+	if opts.schemaPath == "" {
 		return fmt.Errorf("--schema is required")
 	}
-	if len(configPaths) == 0 {
-		return fmt.Errorf("at least one --config is required")
+	if len(opts.configPaths) == 0 && len(opts.merge) == 0 && !opts.gitMode() {
+		return fmt.Errorf("at least one --config or --merge is required")
+	}
+	if len(opts.merge) == 1 {
+		return fmt.Errorf("--merge requires at least two files")
+	}
+	if opts.jobs < 0 {
+		return fmt.Errorf("--jobs must not be negative")
+	}
+	if opts.maxErrors < 0 {
+		return fmt.Errorf("--max-errors must not be negative")
+	}
+	if _, err := parseConcreteness(opts.concrete); err != nil {
+		return fmt.Errorf("--concreteness: %w", err)
+	}
+	if _, err := parseClosedness(opts.closed); err != nil {
+		return fmt.Errorf("--closedness: %w", err)
+	}
+	if _, err := parseEnvMode(opts.env); err != nil {
+		return fmt.Errorf("--env: %w", err)
+	}
+	if _, err := parseMergeMode(opts.mergeMode); err != nil {
+		return fmt.Errorf("--merge-mode: %w", err)
+	}
+	return validateInjections(opts.data, opts.tags)
+}
+
+// runValidation runs the validation, streams the results and exits
+func runValidation(opts cliOptions) {
+	reporter, err := NewReporter(opts.format, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	configPaths, err := collectConfigPaths(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(configPaths) == 0 && len(opts.merge) == 0 {
+		fmt.Fprintf(os.Stderr, "No config files to validate\n")
+		os.Exit(0)
+	}
+
+	contextPaths, err := collectContextConfigPaths(opts, configPaths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cacheDir, err := resolveCacheDir(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	concreteness, _ := parseConcreteness(opts.concrete)
+	closedness, _ := parseClosedness(opts.closed)
+	env, _ := parseEnvMode(opts.env)
+	mergeMode, _ := parseMergeMode(opts.mergeMode)
+	validationOpts := Options{
+		Jobs:         opts.jobs,
+		FailFast:     opts.failFast,
+		MaxErrors:    opts.maxErrors,
+		CacheDir:     cacheDir,
+		Merge:        opts.merge,
+		MergeMode:    mergeMode,
+		Context:      contextPaths,
+		Staged:       opts.staged,
+		Concreteness: concreteness,
+		Closedness:   closedness,
+		Env:          env,
+		Data:         opts.data,
+		Tags:         opts.tags,
+		Values:       opts.values,
+	}
+	if err := setupBaseline(opts, &validationOpts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	summary := Run(opts.schemaPath, configPaths, validationOpts, reporter)
+
+	if skipped := summary.Skipped(); skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Validation stopped early: %d files not validated\n", skipped)
+	}
+
+	if opts.writeBaseline != "" {
+		if err := validationOpts.RecordBaseline.Write(opts.writeBaseline); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Baseline written to %s\n", opts.writeBaseline)
+		os.Exit(0)
+	}
+
+	exitCode := determineExitCode(summary)
+	os.Exit(exitCode)
+}
+
+// setupBaseline loads the baseline to apply and prepares the baseline to record
+func setupBaseline(opts cliOptions, validationOpts *Options) error {
+	if opts.baseline != "" {
+		baseline, err := LoadBaseline(opts.baseline)
+		if err != nil {
+			return err
+		}
+		validationOpts.Baseline = baseline
+	}
+	if opts.writeBaseline != "" {
+		validationOpts.RecordBaseline = NewBaseline()
 	}
 	return nil
 }
 
-// runValidation runs the validation and handles the results
-func runValidation(schemaPath string, configPaths []string) {
-	results := ValidateFiles(schemaPath, configPaths)
-	output := FormatResults(results)
-	fmt.Print(output)
+// resolveCacheDir returns the cache directory to use, or an empty string when caching is disabled
+func resolveCacheDir(opts cliOptions) (string, error) {
+	if opts.cacheDir != "" {
+		return opts.cacheDir, nil
+	}
+	if !opts.cache {
+		return "", nil
+	}
+	return defaultCacheDir()
+}
+
+// determineExitCode determines the exit code based on the validation summary
+func determineExitCode(summary Summary) int {
+	if summary.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// subcommandOptions holds the options shared by the subcommands
+type subcommandOptions struct {
+	schemaPath  string
+	configPaths stringSlice
+	data        stringSlice
+	tags        stringSlice
+	values      stringSlice
+}
+
+// checks returns the check options of a subcommand
+func (o subcommandOptions) checks() checkOptions {
+	return checkOptions{data: o.data, tags: o.tags, values: o.values}
+}
+
+// newSubcommandFlags creates the flag set of a subcommand with the shared options
+func newSubcommandFlags(name string, description string, opts *subcommandOptions) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.StringVar(&opts.schemaPath, "schema", "", "Path to CUE schema file (required)")
+	flags.Var(&opts.configPaths, "config", "Path, directory or glob pattern of config files (can be specified multiple times)")
+	flags.Var(&opts.data, "data", "Data file filled into a top-level schema field, as name=path.yaml (can be specified multiple times)")
+	flags.Var(&opts.tags, "tag", "Value of a CUE @tag() attribute in the schema, as key=value (can be specified multiple times)")
+	flags.Var(&opts.values, "values", "Values file used to render *.tmpl configs, merged in order (can be specified multiple times)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "cint %s - %s\n\n", name, description)
+		fmt.Fprintf(os.Stderr, "Usage: %s %s --schema=<schema.cue> --config=<config.yaml> [--config=<config2.yaml>...]\n\n", filepath.Base(os.Args[0]), name)
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseSubcommandArgs parses the arguments of a subcommand and returns the
+// config files to process, exiting on invalid arguments
+func parseSubcommandArgs(flags *flag.FlagSet, args []string, opts *subcommandOptions, check func() error) []string {
+	flags.Parse(args)
+
+	err := check()
+	if err == nil && opts.schemaPath == "" {
+		err = fmt.Errorf("--schema is required")
+	}
+	if err == nil && len(opts.configPaths) == 0 {
+		err = fmt.Errorf("at least one --config is required")
+	}
+	if err == nil {
+		err = validateInjections(opts.data, opts.tags)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	configPaths, err := collectConfigPaths(cliOptions{configPaths: opts.configPaths, values: opts.values})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return configPaths
+}
+
+// reportInvalid writes the errors of a file that could not be processed to stderr
+func reportInvalid(result ValidationResult) {
+	var output strings.Builder
+	formatSingleResult(&output, result)
+	fmt.Fprint(os.Stderr, output.String())
+}
+
+// runExport writes every config file unified with the schema, including
+// defaults, to stdout and exits. Invalid files are reported on stderr.
+func runExport(args []string) {
+	var opts subcommandOptions
+	var format string
+
+	flags := newSubcommandFlags("export", "Write configs with schema defaults applied", &opts)
+	flags.StringVar(&format, "format", "yaml", "Output format (yaml, json)")
+	configPaths := parseSubcommandArgs(flags, args, &opts, func() error {
+		if !slices.Contains(exportFormats, format) {
+			return fmt.Errorf("--format must be one of %s", strings.Join(exportFormats, ", "))
+		}
+		return nil
+	})
 
-	exitCode := determineExitCode(results)
+	v := newValidator(opts.schemaPath, opts.checks(), nil)
+	exitCode, written := 0, 0
+	for _, configPath := range configPaths {
+		data, result := exportConfig(v, configPath, format)
+		if data == nil {
+			reportInvalid(result)
+			exitCode = 1
+			continue
+		}
+		if format == "yaml" && written > 0 {
+			fmt.Println("---")
+		}
+		os.Stdout.Write(data)
+		written++
+	}
 	os.Exit(exitCode)
 }
 
-// determineExitCode determines the exit code based on validation results
-func determineExitCode(results []ValidationResult) int {
-	for _, result := range results {
+// runDiff prints a unified diff between every config file and the same config
+// with the schema defaults applied, and exits. Invalid files are reported on stderr.
+func runDiff(args []string) {
+	var opts subcommandOptions
+
+	flags := newSubcommandFlags("diff", "Show the defaults the schema applies to configs", &opts)
+	configPaths := parseSubcommandArgs(flags, args, &opts, func() error { return nil })
+
+	v := newValidator(opts.schemaPath, opts.checks(), nil)
+	exitCode := 0
+	for _, configPath := range configPaths {
+		diff, result := diffConfig(v, configPath)
 		if !result.IsValid {
-			return 1
+			reportInvalid(result)
+			exitCode = 1
+			continue
 		}
+		fmt.Print(diff)
 	}
-	return 0
+	os.Exit(exitCode)
 }
 
 // stringSlice implements flag.Value for multiple string flags
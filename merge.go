@@ -0,0 +1,52 @@
+package main
+
+import "cuelang.org/go/cue"
+
+// MergeMode controls how the -merge files are combined into one config
+type MergeMode string
+
+const (
+	MergeUnify    MergeMode = "unify"    // Files are unified, setting a field to different values is a conflict
+	MergeOverride MergeMode = "override" // Later files override the values of earlier files
+)
+
+// mergeModes lists the supported modes in the order they are documented
+var mergeModes = []MergeMode{MergeUnify, MergeOverride}
+
+// parseMergeMode parses a merge mode, where an empty string is the default mode
+func parseMergeMode(s string) (MergeMode, error) {
+	return parseMode("merge mode", s, mergeModes, MergeUnify)
+}
+
+// mergeConfig combines the config of a later file with the config merged so far
+func (m MergeMode) mergeConfig(base cue.Value, overlay cue.Value) cue.Value {
+	if m == MergeOverride {
+		return overlayConfig(base, overlay)
+	}
+	return base.Unify(overlay)
+}
+
+// overlayConfig merges overlay into base like kustomize: values of overlay
+// replace those of base, except that structs in both are merged field by field.
+// The result is built from overlay, so that every value keeps the position of
+// the file that provides it.
+func overlayConfig(base cue.Value, overlay cue.Value) cue.Value {
+	if base.IncompleteKind() != cue.StructKind || overlay.IncompleteKind() != cue.StructKind {
+		return overlay
+	}
+
+	iter, err := base.Fields()
+	if err != nil {
+		return overlay
+	}
+	merged := overlay
+	for iter.Next() {
+		path := cue.MakePath(iter.Selector())
+		value := iter.Value()
+		if overlayValue := overlay.LookupPath(path); overlayValue.Exists() {
+			value = overlayConfig(value, overlayValue)
+		}
+		merged = merged.FillPath(path, value)
+	}
+	return merged
+}
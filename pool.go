@@ -0,0 +1,86 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// validateConcurrently validates config files with up to jobs workers created by
+// newWorker and passes each result to emit in input order. Validation stops once
+// emit returns false.
+func validateConcurrently(configPaths []string, jobs int, newWorker func() *validator, emit func(ValidationResult) bool) {
+	jobs = normalizeJobs(jobs, len(configPaths))
+	if jobs <= 1 {
+		validateSequentially(configPaths, newWorker, emit)
+		return
+	}
+
+	// Every file gets its own buffered slot so workers never block on a
+	// result that the in-order consumer is not ready for yet
+	slots := make([]chan ValidationResult, len(configPaths))
+	for i := range slots {
+		slots[i] = make(chan ValidationResult, 1)
+	}
+
+	var stopped atomic.Bool
+	indexes := make(chan int)
+	go dispatchIndexes(indexes, len(configPaths), &stopped)
+
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v := newWorker()
+			for i := range indexes {
+				slots[i] <- v.validate(configPaths[i])
+			}
+		}()
+	}
+
+	for i := range configPaths {
+		if !emit(<-slots[i]) {
+			break
+		}
+	}
+
+	stopped.Store(true)
+	wg.Wait()
+}
+
+// validateSequentially validates config files one by one with a single validator
+func validateSequentially(configPaths []string, newWorker func() *validator, emit func(ValidationResult) bool) {
+	if len(configPaths) == 0 {
+		return
+	}
+
+	v := newWorker()
+	for _, configPath := range configPaths {
+		if !emit(v.validate(configPath)) {
+			return
+		}
+	}
+}
+
+// dispatchIndexes sends file indexes to the workers until all are sent or stopped is set
+func dispatchIndexes(indexes chan<- int, count int, stopped *atomic.Bool) {
+	defer close(indexes)
+	for i := range count {
+		if stopped.Load() {
+			return
+		}
+		indexes <- i
+	}
+}
+
+// normalizeJobs resolves the requested number of workers for the given number of files
+func normalizeJobs(jobs int, files int) int {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs > files {
+		jobs = files
+	}
+	return jobs
+}
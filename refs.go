@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"cuelang.org/go/cue"
+)
+
+// maxReferenceDepth limits how deep hasReferences follows recursive schemas
+const maxReferenceDepth = 32
+
+// reference is a config value of a field marked with @cint(ref=...)
+type reference struct {
+	target string // Path of the fields that define the referenced values
+	value  string
+	field  string
+	pos    cue.Value // Value the reference was read from, for its position
+	attrs  fieldAttributes
+}
+
+// referenceIndex collects the references of a set of configs and the values
+// they can refer to
+type referenceIndex struct {
+	references []reference
+	defined    map[string]map[string]bool // Values by target path
+}
+
+// hasReferences reports whether v or a field below it is marked with
+// @cint(ref=...), following recursive definitions up to maxReferenceDepth
+func hasReferences(v cue.Value, depth int) bool {
+	if depth > maxReferenceDepth {
+		return false
+	}
+	if lookupFieldAttributes(v, cue.MakePath()).ref != "" {
+		return true
+	}
+
+	if iter, err := v.Fields(cue.Optional(true)); err == nil {
+		for iter.Next() {
+			if hasReferences(iter.Value(), depth+1) {
+				return true
+			}
+		}
+	}
+	for _, sel := range []cue.Selector{cue.AnyIndex, cue.AnyString} {
+		if elem := v.LookupPath(cue.MakePath(sel)); elem.Exists() && hasReferences(elem, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// add records the references made by config, looked up in unified, which is
+// the config unified with #Config
+func (r *referenceIndex) add(config cue.Value, unified cue.Value) {
+	walkFields(config, func(path []cue.Selector, field cue.Value) bool {
+		attrs := lookupFieldAttributes(unified, cue.MakePath(path...))
+		if attrs.ref == "" {
+			return true
+		}
+
+		// A list refers to a value with each of its elements
+		if list, err := field.List(); err == nil {
+			for list.Next() {
+				r.addReference(attrs, append(slices.Clip(path), list.Selector()), list.Value())
+			}
+		} else {
+			r.addReference(attrs, path, field)
+		}
+		return false
+	})
+}
+
+// addReference records the reference made by the concrete scalar value v at path
+func (r *referenceIndex) addReference(attrs fieldAttributes, path []cue.Selector, v cue.Value) {
+	if key, ok := referenceKey(v); ok {
+		r.references = append(r.references, reference{target: attrs.ref, value: key, field: selectorsToField(path), pos: v, attrs: attrs})
+	}
+}
+
+// define records the values unified defines for every target path referred
+// to so far. Call it after add has been called for every config.
+func (r *referenceIndex) define(unified cue.Value) {
+	if r.defined == nil {
+		r.defined = make(map[string]map[string]bool)
+	}
+	for _, ref := range r.references {
+		if r.defined[ref.target] == nil {
+			r.defined[ref.target] = make(map[string]bool)
+		}
+	}
+	for target, values := range r.defined {
+		collectTargetValues(unified, strings.Split(target, "."), func(v cue.Value) {
+			if key, ok := referenceKey(v); ok {
+				values[key] = true
+			}
+		})
+	}
+}
+
+// dangling returns an error for every reference to a value no config defines
+func (r *referenceIndex) dangling() []ValidationError {
+	var danglingErrors []ValidationError
+	for _, ref := range r.references {
+		defined := r.defined[ref.target]
+		if defined[ref.value] {
+			continue
+		}
+
+		problem := fmt.Sprintf("reference `%s` is not defined by any `%s`", ref.value, ref.target)
+		if closest := closestName(ref.value, slices.Sorted(maps.Keys(defined))); closest != "" {
+			problem += fmt.Sprintf("; did you mean `%s`?", closest)
+		}
+		if ref.attrs.msg != "" {
+			problem = ref.attrs.msg
+		}
+
+		danglingErrors = append(danglingErrors, ValidationError{
+			File:     ref.pos.Pos().Filename(),
+			Line:     ref.pos.Pos().Line(),
+			Field:    ref.field,
+			Problem:  problem,
+			Code:     CodeDanglingReference,
+			Severity: ref.attrs.severity,
+		})
+	}
+	return danglingErrors
+}
+
+// collectTargetValues calls fn for every value at path below v. Lists on the
+// way are expanded, so that services.name matches the name of every service.
+func collectTargetValues(v cue.Value, path []string, fn func(cue.Value)) {
+	if list, err := v.List(); err == nil {
+		for list.Next() {
+			collectTargetValues(list.Value(), path, fn)
+		}
+		return
+	}
+	if len(path) == 0 {
+		fn(v)
+		return
+	}
+	if next := v.LookupPath(cue.MakePath(cue.Str(path[0]))); next.Exists() {
+		collectTargetValues(next, path[1:], fn)
+	}
+}
+
+// referenceKey returns the text by which a concrete scalar value is referred to
+func referenceKey(v cue.Value) (string, bool) {
+	if !v.IsConcrete() || v.Kind() == cue.StructKind || v.Kind() == cue.ListKind {
+		return "", false
+	}
+	if s, err := v.String(); err == nil {
+		return s, true
+	}
+	return fmt.Sprint(v), true
+}
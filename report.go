@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Reporter receives validation results while files are being validated
+type Reporter interface {
+	OnStart(total int)
+	OnResult(result ValidationResult)
+	OnFinish(summary Summary)
+}
+
+// Summary describes a finished validation run
+type Summary struct {
+	Total     int `json:"total"`     // Number of files requested
+	Validated int `json:"validated"` // Number of files actually validated
+	Failed    int `json:"failed"`    // Number of files that failed validation
+}
+
+// Skipped returns the number of files that were not validated because validation stopped early
+func (s Summary) Skipped() int {
+	return s.Total - s.Validated
+}
+
+// add records a single validation result
+func (s *Summary) add(result ValidationResult) {
+	s.Validated++
+	if !result.IsValid {
+		s.Failed++
+	}
+}
+
+// NewReporter creates a reporter for the given output format
+func NewReporter(format string, w io.Writer) (Reporter, error) {
+	switch format {
+	case "text":
+		return &textReporter{w: w}, nil
+	case "jsonl":
+		return &jsonlReporter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s (supported: text, jsonl)", format)
+	}
+}
+
+// textReporter writes human-readable results
+type textReporter struct {
+	w io.Writer
+}
+
+func (r *textReporter) OnStart(total int) {}
+
+func (r *textReporter) OnResult(result ValidationResult) {
+	var output strings.Builder
+	formatSingleResult(&output, result)
+	io.WriteString(r.w, output.String())
+}
+
+func (r *textReporter) OnFinish(summary Summary) {}
+
+// jsonlReporter writes one JSON object per line for every event
+type jsonlReporter struct {
+	enc *json.Encoder
+}
+
+// jsonlStart is the first line of JSONL output
+type jsonlStart struct {
+	Type  string `json:"type"`
+	Total int    `json:"total"`
+}
+
+// jsonlResult is the JSONL line written for every validated file
+type jsonlResult struct {
+	Type string `json:"type"`
+	ValidationResult
+}
+
+// jsonlSummary is the last line of JSONL output
+type jsonlSummary struct {
+	Type string `json:"type"`
+	Summary
+}
+
+func (r *jsonlReporter) OnStart(total int) {
+	r.enc.Encode(jsonlStart{Type: "start", Total: total})
+}
+
+func (r *jsonlReporter) OnResult(result ValidationResult) {
+	r.enc.Encode(jsonlResult{Type: "result", ValidationResult: result})
+}
+
+func (r *jsonlReporter) OnFinish(summary Summary) {
+	r.enc.Encode(jsonlSummary{Type: "summary", Summary: summary})
+}
+
+// resultCollector keeps every result in memory
+type resultCollector struct {
+	results []ValidationResult
+}
+
+func (c *resultCollector) OnStart(total int) {
+	c.results = make([]ValidationResult, 0, total)
+}
+
+func (c *resultCollector) OnResult(result ValidationResult) {
+	c.results = append(c.results, result)
+}
+
+func (c *resultCollector) OnFinish(summary Summary) {}
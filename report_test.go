@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// eventRecorder records the events a reporter receives
+type eventRecorder struct {
+	events []string
+}
+
+func (r *eventRecorder) OnStart(total int) { r.events = append(r.events, "start") }
+func (r *eventRecorder) OnResult(result ValidationResult) {
+	r.events = append(r.events, result.FileName)
+}
+func (r *eventRecorder) OnFinish(summary Summary) { r.events = append(r.events, "summary") }
+
+// writeReportFiles writes a schema with a valid and an invalid config to the
+// current directory
+func writeReportFiles(t *testing.T) {
+	t.Helper()
+	files := map[string]string{
+		"schema.cue":  `#Config: {name: string, replicas: int & >=1}`,
+		"valid.yaml":  "name: web\nreplicas: 2\n",
+		"broken.yaml": "name: web\nreplicas: 0\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestRunEventOrder(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeReportFiles(t)
+
+	recorder := &eventRecorder{}
+	opts := DefaultOptions()
+	opts.Jobs = 2
+	summary := Run("schema.cue", []string{"valid.yaml", "broken.yaml"}, opts, recorder)
+
+	want := []string{"start", "valid.yaml", "broken.yaml", "summary"}
+	if !slices.Equal(recorder.events, want) {
+		t.Errorf("events = %v, want %v", recorder.events, want)
+	}
+	if summary != (Summary{Total: 2, Validated: 2, Failed: 1}) {
+		t.Errorf("summary = %+v", summary)
+	}
+}
+
+func TestJSONLReporter(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeReportFiles(t)
+
+	var out bytes.Buffer
+	reporter, err := NewReporter("jsonl", &out)
+	if err != nil {
+		t.Fatalf("NewReporter failed: %v", err)
+	}
+	Run("schema.cue", []string{"valid.yaml", "broken.yaml"}, DefaultOptions(), reporter)
+
+	var lines []map[string]any
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4: %s", len(lines), out.String())
+	}
+
+	// Numbers are decoded as float64
+	wantStart := map[string]any{"type": "start", "total": 2.0}
+	wantSummary := map[string]any{"type": "summary", "total": 2.0, "validated": 2.0, "failed": 1.0}
+	if !reflect.DeepEqual(lines[0], wantStart) {
+		t.Errorf("start = %v, want %v", lines[0], wantStart)
+	}
+	if !reflect.DeepEqual(lines[3], wantSummary) {
+		t.Errorf("summary = %v, want %v", lines[3], wantSummary)
+	}
+
+	wantValid := map[string]any{"type": "result", "file": "valid.yaml", "valid": true, "errors": []any{}}
+	if !reflect.DeepEqual(lines[1], wantValid) {
+		t.Errorf("valid result = %v, want %v", lines[1], wantValid)
+	}
+
+	broken := lines[2]
+	if broken["type"] != "result" || broken["file"] != "broken.yaml" || broken["valid"] != false {
+		t.Errorf("broken result = %v", broken)
+	}
+	errs, _ := broken["errors"].([]any)
+	if len(errs) != 1 {
+		t.Fatalf("broken result errors = %v, want one error", broken["errors"])
+	}
+	brokenErr, _ := errs[0].(map[string]any)
+	for key, want := range map[string]any{"line": 2.0, "field": "replicas", "code": "CINT011", "severity": "error"} {
+		if brokenErr[key] != want {
+			t.Errorf("error %s = %v, want %v", key, brokenErr[key], want)
+		}
+	}
+	if _, ok := brokenErr["problem"].(string); !ok {
+		t.Errorf("error has no problem: %v", brokenErr)
+	}
+	for _, key := range []string{"file", "detail"} {
+		if _, ok := brokenErr[key]; ok {
+			t.Errorf("error has empty field %s: %v", key, brokenErr)
+		}
+	}
+	if _, ok := broken["omitted_errors"]; ok {
+		t.Errorf("result has empty field omitted_errors: %v", broken)
+	}
+}
+
+func TestTextReporter(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeReportFiles(t)
+	configPaths := []string{"valid.yaml", "broken.yaml"}
+
+	var out bytes.Buffer
+	reporter, err := NewReporter("text", &out)
+	if err != nil {
+		t.Fatalf("NewReporter failed: %v", err)
+	}
+	Run("schema.cue", configPaths, DefaultOptions(), reporter)
+
+	want := FormatResults(ValidateFiles("schema.cue", configPaths))
+	if out.String() != want {
+		t.Errorf("text output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestNewReporterWithUnsupportedFormat(t *testing.T) {
+	if _, err := NewReporter("xml", &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
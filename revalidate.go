@@ -0,0 +1,120 @@
+package main
+
+import (
+	"slices"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/errors"
+)
+
+// maxValidationPasses limits how often validateFields validates a config
+const maxValidationPasses = 4
+
+// validateFields validates vc.unified, the config unified with #Config without
+// the fields at removed, and returns the errors of the other fields. CUE only
+// reports incomplete values, such as missing required fields, when nothing else
+// fails, so the config is validated again without the failed fields until no
+// more errors come up. Otherwise a warning could hide a missing field.
+// Removed fields are set in the config, so they are not reported as missing.
+func validateFields(vc validationContext, checks checkOptions, removed [][]cue.Selector) []ValidationError {
+	var validationErrors []ValidationError
+
+	pass := vc
+	for i := range maxValidationPasses {
+		if i > 0 {
+			pass.unified = vc.definition.Unify(withoutFields(vc.config, removed))
+		}
+		err := pass.unified.Validate(checks.concreteness.validateOptions()...)
+		if err == nil {
+			break
+		}
+
+		var reported errors.Error
+		var failed [][]cue.Selector
+		hidesErrors, isolated := false, true
+		for _, e := range errors.Errors(err) {
+			path := valuePath(e.Path()).Selectors()
+			if slices.ContainsFunc(removed, func(prefix []cue.Selector) bool { return hasSelectorPrefix(path, prefix) }) {
+				continue
+			}
+			reported = errors.Append(reported, e)
+			failed = append(failed, path)
+			hidesErrors = hidesErrors || classifyError(e) != CodeMissingField
+			isolated = isolated && len(path) > 0
+		}
+		if reported == nil {
+			break
+		}
+		validationErrors = append(validationErrors, extractValidationErrors(reported, pass)...)
+
+		// Incomplete values hide nothing, and errors of the whole config cannot be left out
+		if !hidesErrors || !isolated {
+			break
+		}
+		removed = append(slices.Clip(removed), failed...)
+	}
+
+	return validationErrors
+}
+
+// withoutFields returns config without the fields at paths. List elements are
+// replaced by _ instead, so that the other elements keep their index. Scalars
+// are copied from config and keep their positions, while rebuilt structs and
+// their labels have none.
+func withoutFields(config cue.Value, paths [][]cue.Selector) cue.Value {
+	return withoutFieldsAt(config, nil, paths)
+}
+
+// withoutFieldsAt removes the fields at paths from v, which is located at path
+func withoutFieldsAt(v cue.Value, path []cue.Selector, paths [][]cue.Selector) cue.Value {
+	below := slices.ContainsFunc(paths, func(p []cue.Selector) bool {
+		return len(p) > len(path) && hasSelectorPrefix(p, path)
+	})
+	if !below {
+		return v
+	}
+	isRemoved := func(p []cue.Selector) bool {
+		return slices.ContainsFunc(paths, func(removed []cue.Selector) bool { return hasSelectorPrefix(p, removed) })
+	}
+
+	ctx := v.Context()
+	switch v.IncompleteKind() {
+	case cue.StructKind:
+		iter, err := v.Fields()
+		if err != nil {
+			return v
+		}
+		result := ctx.CompileString("{}")
+		for iter.Next() {
+			fieldPath := append(slices.Clip(path), iter.Selector())
+			if !isRemoved(fieldPath) {
+				result = result.FillPath(cue.MakePath(iter.Selector()), withoutFieldsAt(iter.Value(), fieldPath, paths))
+			}
+		}
+		return result
+	case cue.ListKind:
+		list, err := v.List()
+		if err != nil {
+			return v
+		}
+		var elements []cue.Value
+		for list.Next() {
+			elementPath := append(slices.Clip(path), list.Selector())
+			if isRemoved(elementPath) {
+				elements = append(elements, ctx.CompileString("_"))
+			} else {
+				elements = append(elements, withoutFieldsAt(list.Value(), elementPath, paths))
+			}
+		}
+		return ctx.NewList(elements...)
+	default:
+		return v
+	}
+}
+
+// hasSelectorPrefix checks if path starts with the selectors of prefix
+func hasSelectorPrefix(path []cue.Selector, prefix []cue.Selector) bool {
+	return len(path) >= len(prefix) && slices.EqualFunc(path[:len(prefix)], prefix, func(a, b cue.Selector) bool {
+		return a.String() == b.String()
+	})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/errors"
+)
+
+// isFieldNotAllowed checks if a CUE error reports a field that a closed struct does not allow
+func isFieldNotAllowed(e errors.Error) bool {
+	format, _ := e.Msg()
+	return format == "field not allowed"
+}
+
+// fieldSuggestion returns a hint for the disallowed field at errorPath: the
+// closest field allowed by the schema definition, or the list of allowed fields
+// when none is close enough
+func fieldSuggestion(definition cue.Value, errorPath []string) string {
+	path := valuePath(errorPath).Selectors()
+	if len(path) == 0 || path[len(path)-1].LabelType() != cue.StringLabel {
+		return ""
+	}
+	name := path[len(path)-1].Unquoted()
+
+	allowed := allowedFieldNames(definition, path[:len(path)-1])
+	if len(allowed) == 0 {
+		return ""
+	}
+
+	if closest := closestName(name, allowed); closest != "" {
+		return fmt.Sprintf("did you mean `%s`?", closest)
+	}
+	if len(allowed) > maxListedFields {
+		return fmt.Sprintf("allowed fields: `%s`, ...", strings.Join(allowed[:maxListedFields], "`, `"))
+	}
+	return fmt.Sprintf("allowed fields: `%s`", strings.Join(allowed, "`, `"))
+}
+
+// maxListedFields limits how many allowed fields are listed in a hint
+const maxListedFields = 10
+
+// allowedFieldNames returns the regular fields the schema declares at a config path
+func allowedFieldNames(definition cue.Value, path []cue.Selector) []string {
+	parent, ok := lookupSchemaValue(definition, path)
+	if !ok {
+		return nil
+	}
+
+	iter, err := parent.Fields(cue.Optional(true))
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for iter.Next() {
+		if sel := iter.Selector(); sel.LabelType() == cue.StringLabel {
+			names = append(names, sel.Unquoted())
+		}
+	}
+	return names
+}
+
+// closestName returns the candidate with the smallest edit distance to name,
+// or "" if every candidate needs too many edits
+func closestName(name string, candidates []string) string {
+	best, bestDistance := "", max(1, len(name)/3)+1
+	for _, candidate := range candidates {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(candidate)); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// lookupSchemaValue looks up the schema value for a config path. List indexes are
+// mapped to the list element constraint and unknown labels to pattern constraints.
+func lookupSchemaValue(definition cue.Value, path []cue.Selector) (cue.Value, bool) {
+	v := definition
+	for _, sel := range path {
+		var next cue.Value
+		switch sel.LabelType() {
+		case cue.IndexLabel:
+			next = v.LookupPath(cue.MakePath(cue.AnyIndex))
+		case cue.StringLabel:
+			next = v.LookupPath(cue.MakePath(cue.Str(sel.Unquoted()).Optional()))
+			if !next.Exists() {
+				next = v.LookupPath(cue.MakePath(cue.AnyString))
+			}
+		default:
+			return cue.Value{}, false
+		}
+		if !next.Exists() {
+			return cue.Value{}, false
+		}
+		v = next
+	}
+	return v, true
+}
+
+// editDistance computes the optimal string alignment distance between two
+// strings: the Levenshtein distance where swapping adjacent characters is a
+// single edit, because that is a common typo
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// d[i][j] is the distance between the first i runes of a and the first j runes of b
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
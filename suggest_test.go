@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"helthCheck", "healthCheck", 1},
+		{"protcol", "protocol", 1},
+		{"kitten", "sitting", 3},
+		{"nmae", "name", 1},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosestName(t *testing.T) {
+	candidates := []string{"name", "healthCheck", "ports", "replicas"}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"helthCheck", "healthCheck"},
+		{"HealthCheck", "healthCheck"},
+		{"port", "ports"},
+		{"replica", "replicas"},
+		{"nmae", "name"},
+		{"image", ""},
+		{"zzzzzz", ""},
+	}
+
+	for _, tt := range tests {
+		if got := closestName(tt.name, candidates); got != tt.want {
+			t.Errorf("closestName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
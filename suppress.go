@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// suppression is an inline comment that hides matching validation errors.
+//
+//	# cint:ignore-next-line [field=<path>] [code=<code>] [reason="..."]
+//	# cint:ignore [field=<path>] [code=<code>] [reason="..."]
+//
+// In JSONC files the comments start with // instead of #.
+type suppression struct {
+	file       string // File containing the comment
+	line       int    // Line of the comment
+	targetLine int    // Line whose errors are suppressed (0 means the whole file)
+	field      string // Field whose errors are suppressed, including its children (empty means any)
+	code       Code   // Code of the suppressed errors (empty means any)
+	reason     string
+	used       bool
+}
+
+var (
+	suppressionPattern = regexp.MustCompile(`(?:#|//)\s*cint:([a-z-]+)(.*)$`)
+	suppressionArg     = regexp.MustCompile(`^([a-z]+)=("(?:[^"\\]|\\.)*"|\S+)`)
+	commentOnlyLine    = regexp.MustCompile(`^\s*(?:#|//|$)`)
+)
+
+// parseSuppressions finds the suppression comments in a config file. Malformed
+// comments are returned as errors so that typos do not silently suppress nothing.
+func parseSuppressions(configPath string, configData []byte) ([]*suppression, []ValidationError) {
+	lines := strings.Split(string(configData), "\n")
+
+	var suppressions []*suppression
+	var problems []ValidationError
+	for i, text := range lines {
+		match := suppressionPattern.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+
+		s, err := parseSuppression(match[1], match[2])
+		if err != nil {
+			problems = append(problems, ValidationError{File: configPath, Line: i + 1, Problem: err.Error(), Code: CodeInvalidSuppression})
+			continue
+		}
+
+		s.file = configPath
+		s.line = i + 1
+		if match[1] == "ignore-next-line" {
+			s.targetLine = nextContentLine(lines, i+1)
+		}
+		suppressions = append(suppressions, s)
+	}
+
+	return suppressions, problems
+}
+
+// parseSuppression parses the directive and arguments of a suppression comment
+func parseSuppression(directive string, args string) (*suppression, error) {
+	if directive != "ignore" && directive != "ignore-next-line" {
+		return nil, fmt.Errorf("unknown suppression cint:%s (supported: cint:ignore, cint:ignore-next-line)", directive)
+	}
+
+	s := &suppression{}
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		match := suppressionArg.FindStringSubmatch(args)
+		if match == nil {
+			return nil, fmt.Errorf("invalid cint:%s argument: %s", directive, args)
+		}
+		args = args[len(match[0]):]
+
+		value := match[2]
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cint:%s argument: %s", directive, match[0])
+			}
+			value = unquoted
+		}
+
+		switch match[1] {
+		case "field":
+			s.field = value
+		case "code":
+			s.code = Code(strings.ToUpper(value))
+		case "reason":
+			s.reason = value
+		default:
+			return nil, fmt.Errorf("unknown cint:%s argument: %s", directive, match[1])
+		}
+	}
+
+	if directive == "ignore" && s.field == "" && s.code == "" {
+		return nil, fmt.Errorf("cint:ignore requires a field=<path> or code=<code> argument")
+	}
+	return s, nil
+}
+
+// nextContentLine returns the line number of the first line at or after index
+// start that is neither blank nor a comment, or 0 if there is none
+func nextContentLine(lines []string, start int) int {
+	for i := start; i < len(lines); i++ {
+		if !commentOnlyLine.MatchString(lines[i]) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// applySuppressions removes the errors matched by suppressions and adds an error
+// for every suppression that did not match anything. The errors include the
+// missing fields that CUE only reports without the failed fields, so that
+// suppressing the only reported error does not make a config valid.
+func applySuppressions(validationErrors []ValidationError, suppressions []*suppression) []ValidationError {
+	var kept []ValidationError
+	for _, err := range validationErrors {
+		if !suppress(err, suppressions) {
+			kept = append(kept, err)
+		}
+	}
+
+	for _, s := range suppressions {
+		if !s.used {
+			kept = append(kept, ValidationError{
+				File:    s.file,
+				Line:    s.line,
+				Field:   s.field,
+				Problem: "unused suppression: no matching validation error",
+				Code:    CodeUnusedSuppression,
+			})
+		}
+	}
+
+	return kept
+}
+
+// suppress marks every suppression matching err as used and reports whether there was one
+func suppress(err ValidationError, suppressions []*suppression) bool {
+	suppressed := false
+	for _, s := range suppressions {
+		if s.matches(err) {
+			s.used = true
+			suppressed = true
+		}
+	}
+	return suppressed
+}
+
+// matches checks if a suppression applies to a validation error
+func (s *suppression) matches(err ValidationError) bool {
+	if err.File != "" && err.File != s.file {
+		return false
+	}
+	if s.targetLine != 0 && err.Line != s.targetLine {
+		return false
+	}
+	if s.field != "" && err.Field != s.field && !strings.HasPrefix(err.Field, s.field+".") {
+		return false
+	}
+	if s.code != "" && err.Code != s.code {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/encoding/yaml"
+)
+
+// templateExtension marks config files that are rendered with text/template
+// before validation, e.g. service.yaml.tmpl
+const templateExtension = ".tmpl"
+
+// isTemplateFile checks if a file is a template of a supported config file
+func isTemplateFile(path string) bool {
+	ext := filepath.Ext(path)
+	return strings.EqualFold(ext, templateExtension) && isSupportedFile(strings.TrimSuffix(path, ext))
+}
+
+// configExtension returns the extension that determines the format of a config
+// file, which is the extension before .tmpl for templates
+func configExtension(path string) string {
+	if isTemplateFile(path) {
+		path = strings.TrimSuffix(path, filepath.Ext(path))
+	}
+	return strings.ToLower(filepath.Ext(path))
+}
+
+// templateLinePattern matches the line in the errors of text/template, e.g.
+// "template: app.yaml.tmpl:3:9: executing ..."
+var templateLinePattern = regexp.MustCompile(`^template: [^:]*:(\d+)`)
+
+// renderTemplate renders a config template with the merged values files as
+// .Values, like Helm. Missing values render as empty strings, and accessing a
+// field of a missing value is an error.
+func renderTemplate(ctx *cue.Context, templatePath string, templateData []byte, valuesPaths []string) ([]byte, error) {
+	values, err := loadValues(ctx, valuesPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(filepath.Base(templatePath)).
+		Option("missingkey=zero").
+		Funcs(templateFuncs(ctx)).
+		Funcs(template.FuncMap{emptyIfNilFunc: emptyIfNil}).
+		Parse(string(templateData))
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tmpl.Templates() {
+		printMissingAsEmpty(t.Tree, t.Tree.Root)
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, map[string]any{"Values": values}); err != nil {
+		return nil, err
+	}
+	return rendered.Bytes(), nil
+}
+
+// emptyIfNilFunc is the name under which emptyIfNil is added to the pipelines
+// of templates
+const emptyIfNilFunc = "_cintEmptyIfNil"
+
+// emptyIfNil returns an empty string for nil, the value of a missing key with
+// missingkey=zero, which text/template would print as "<no value>"
+func emptyIfNil(v any) any {
+	if v == nil {
+		return ""
+	}
+	return v
+}
+
+// printMissingAsEmpty passes the value of every action below node that prints
+// its pipeline through emptyIfNil
+func printMissingAsEmpty(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			printMissingAsEmpty(tree, child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			return // Assignments print nothing
+		}
+		identifier := parse.NewIdentifier(emptyIfNilFunc).SetTree(tree).SetPos(n.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{identifier}})
+	case *parse.IfNode:
+		printMissingAsEmpty(tree, n.List)
+		printMissingAsEmpty(tree, n.ElseList)
+	case *parse.RangeNode:
+		printMissingAsEmpty(tree, n.List)
+		printMissingAsEmpty(tree, n.ElseList)
+	case *parse.WithNode:
+		printMissingAsEmpty(tree, n.List)
+		printMissingAsEmpty(tree, n.ElseList)
+	}
+}
+
+// templateErrorResult creates the result for a template that could not be rendered
+func templateErrorResult(name string, templatePath string, err error) ValidationResult {
+	result := createErrorResult(name, CodeTemplateError, fmt.Sprintf("failed to render template: %v", err))
+	result.Errors[0].File = templatePath
+	if match := templateLinePattern.FindStringSubmatch(err.Error()); match != nil {
+		result.Errors[0].Line, _ = strconv.Atoi(match[1])
+	}
+	return result
+}
+
+// loadValues reads values files and merges them in order, where later files
+// override the values of earlier ones
+func loadValues(ctx *cue.Context, valuesPaths []string) (map[string]any, error) {
+	values := make(map[string]any)
+	for _, valuesPath := range valuesPaths {
+		v, err := readDataFile(ctx, valuesPath)
+		if err != nil {
+			return nil, fmt.Errorf("loading values: %w", err)
+		}
+		var fileValues map[string]any
+		if err := v.Decode(&fileValues); err != nil {
+			return nil, fmt.Errorf("loading values from %s: %w", valuesPath, err)
+		}
+		mergeValues(values, fileValues)
+	}
+	return values, nil
+}
+
+// mergeValues merges src into dst, recursing into maps present in both
+func mergeValues(dst map[string]any, src map[string]any) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+		if srcIsMap && dstIsMap {
+			mergeValues(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// mapRenderedLines returns the template line of every rendered line, based on
+// a line diff. Unchanged lines map to themselves and added lines to the
+// template line before them. Changed lines map to the replaced template line
+// whose text before its first action starts the rendered line, or else to the
+// replaced template lines in order.
+func mapRenderedLines(templateData []byte, rendered []byte) []int {
+	templateLines := splitLines(templateData)
+
+	var lines []int
+	var removed []int // Template lines replaced by the current change
+	added := 0
+
+	for _, op := range diffLines(templateLines, splitLines(rendered)) {
+		switch op.kind {
+		case ' ':
+			removed, added = nil, 0
+			lines = append(lines, op.oldIndex+1)
+		case '-':
+			removed = append(removed, op.oldIndex+1)
+		case '+':
+			line := max(op.oldIndex, 1)
+			if len(removed) > 0 {
+				line = removed[min(added, len(removed)-1)]
+				if closest, ok := closestTemplateLine(op.text, removed, templateLines); ok {
+					line = closest
+				}
+			}
+			lines = append(lines, line)
+			added++
+		}
+	}
+	return lines
+}
+
+// closestTemplateLine returns the candidate template line with the longest
+// literal text before its first action that starts the rendered line
+func closestTemplateLine(rendered string, candidates []int, templateLines []string) (int, bool) {
+	best, bestLength := 0, 0
+	for _, line := range candidates {
+		literal, _, _ := strings.Cut(templateLines[line-1], "{{")
+		if len(literal) > bestLength && strings.HasPrefix(rendered, literal) {
+			best, bestLength = line, len(literal)
+		}
+	}
+	return best, best > 0
+}
+
+// mapTemplateLines replaces the rendered lines of errors in templates with the
+// template lines that produced them. Errors without a file are in files[0].
+func mapTemplateLines(validationErrors []ValidationError, lineMaps map[string][]int, files []string) []ValidationError {
+	for i, ve := range validationErrors {
+		file := ve.File
+		if file == "" && len(files) == 1 {
+			file = files[0]
+		}
+		if lines, ok := lineMaps[file]; ok && ve.Line > 0 && ve.Line <= len(lines) {
+			validationErrors[i].Line = lines[ve.Line-1]
+		}
+	}
+	return validationErrors
+}
+
+// templateFuncs returns a subset of the Sprig functions commonly used in
+// Helm-style config templates
+func templateFuncs(ctx *cue.Context) template.FuncMap {
+	return template.FuncMap{
+		"default": func(fallback any, given ...any) any {
+			if len(given) == 0 || isEmpty(given[0]) {
+				return fallback
+			}
+			return given[0]
+		},
+		"required": func(msg string, v any) (any, error) {
+			if isEmpty(v) {
+				return nil, errors.New(msg)
+			}
+			return v, nil
+		},
+		"empty": isEmpty,
+		"coalesce": func(values ...any) any {
+			for _, v := range values {
+				if !isEmpty(v) {
+					return v
+				}
+			}
+			return nil
+		},
+		"ternary": func(a, b any, condition bool) any {
+			if condition {
+				return a
+			}
+			return b
+		},
+		"quote":      func(v any) string { return strconv.Quote(toString(v)) },
+		"squote":     func(v any) string { return "'" + toString(v) + "'" },
+		"toString":   toString,
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"indent":     indent,
+		"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+		"b64enc":     func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec": func(s string) (string, error) {
+			data, err := base64.StdEncoding.DecodeString(s)
+			return string(data), err
+		},
+		"list": func(values ...any) []any { return values },
+		"dict": func(pairs ...any) map[string]any {
+			d := make(map[string]any)
+			for i := 0; i+1 < len(pairs); i += 2 {
+				d[toString(pairs[i])] = pairs[i+1]
+			}
+			return d
+		},
+		"join": func(sep string, values []any) string {
+			parts := make([]string, len(values))
+			for i, v := range values {
+				parts[i] = toString(v)
+			}
+			return strings.Join(parts, sep)
+		},
+		"toJson": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"toYaml": func(v any) (string, error) {
+			data, err := yaml.Encode(ctx.Encode(v))
+			return strings.TrimSuffix(string(data), "\n"), err
+		},
+	}
+}
+
+// isEmpty reports whether v is nil or the zero value of its type, as in Sprig
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	default:
+		return rv.IsZero()
+	}
+}
+
+// toString formats a template value as text, with nil as an empty string
+func toString(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// indent prefixes every line of s with n spaces
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cuelang.org/go/cue/cuecontext"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	valuesPath := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(valuesPath, []byte("name: web\nlabels: {}\n"), 0644); err != nil {
+		t.Fatalf("failed to write values file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"value", "name: {{ .Values.name }}", "name: web"},
+		{"missing value", "name: {{ .Values.missing }}", "name: "},
+		{"missing map key", "team: {{ .Values.labels.team }}", "team: "},
+		{"missing value in branch", "{{ if .Values.name }}{{ .Values.missing }}{{ end }}", ""},
+		{"missing value with default", "replicas: {{ .Values.replicas | default 1 }}", "replicas: 1"},
+		{"missing value in variable", "{{ $r := .Values.replicas }}replicas: {{ $r }}", "replicas: "},
+		{"literal text", "# <no value>", "# <no value>"},
+	}
+
+	ctx := cuecontext.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := renderTemplate(ctx, "app.yaml.tmpl", []byte(tt.template), []string{valuesPath})
+			if err != nil {
+				t.Fatalf("renderTemplate failed: %v", err)
+			}
+			if string(rendered) != tt.want {
+				t.Errorf("rendered %q, want %q", rendered, tt.want)
+			}
+		})
+	}
+}
@@ -3,126 +3,423 @@ package main
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"slices"
 	"strings"
 
 	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/cuecontext"
 	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/token"
 	"cuelang.org/go/encoding/json"
 	"cuelang.org/go/encoding/yaml"
 )
 
 // ValidationResult represents the validation result for a single file
 type ValidationResult struct {
-	FileName string
-	IsValid  bool
-	Errors   []ValidationError
+	FileName      string            `json:"file"`
+	IsValid       bool              `json:"valid"`
+	Errors        []ValidationError `json:"errors"`
+	OmittedErrors int               `json:"omitted_errors,omitempty"` // Number of errors dropped because of Options.MaxErrors
 }
 
 // ValidationError represents a single validation error
 type ValidationError struct {
-	Line    int    // Line number in the config file
-	Field   string // Field path (e.g., "spec.replicas")
-	Problem string // Error message from CUE
+	File     string   `json:"file,omitempty"`   // Config file containing Line, only set for merged configs
+	Line     int      `json:"line,omitempty"`   // Line number in the config file
+	Field    string   `json:"field,omitempty"`  // Field path (e.g., "spec.replicas")
+	Problem  string   `json:"problem"`          // Error message from CUE or the schema
+	Detail   string   `json:"detail,omitempty"` // Original CUE message when the schema provides Problem
+	Code     Code     `json:"code,omitempty"`   // Stable identifier of the error class
+	Severity Severity `json:"severity"`         // Whether the error fails validation
+}
+
+// IsWarning reports whether the error is only a warning
+func (e ValidationError) IsWarning() bool {
+	return e.Severity == SeverityWarning
+}
+
+// Options controls how config files are validated
+type Options struct {
+	Jobs      int  // Number of files validated in parallel (0 means number of CPUs)
+	FailFast  bool // Stop after the first failing file
+	MaxErrors int  // Stop after reporting this many errors in total (0 means unlimited)
+
+	CacheDir string // Directory for cached results (empty disables caching)
+
+	Baseline       *Baseline // Known errors that are not reported
+	RecordBaseline *Baseline // Receives every error before Baseline is applied
+
+	Merge     []string  // Config files validated together as a single config after the other files
+	MergeMode MergeMode // How the Merge files are combined (empty means MergeUnify)
+
+	Staged bool // Read config files from the git index instead of the working tree
+
+	Context []string // Config files that are not validated but take part in the cross-file checks, e.g. unchanged files in git mode
+
+	Concreteness Concreteness // How complete configs must be (empty means ConcretenessDefault)
+	Closedness   Closedness   // How undeclared fields are reported (empty means ClosednessDefault)
+	Env          EnvMode      // How ${VAR} placeholders are handled (empty means EnvOff)
+
+	Data   []string // Data files filled into top-level schema fields, as name=path
+	Tags   []string // Values of @tag() attributes in the schema, as key=value
+	Values []string // Values files merged in order and used to render *.tmpl configs
+}
+
+// DefaultOptions returns the options used by ValidateFiles
+func DefaultOptions() Options {
+	return Options{Jobs: 1}
 }
 
 // ValidateFiles validates multiple config files against a CUE schema
 func ValidateFiles(schemaPath string, configPaths []string) []ValidationResult {
-	ctx := cuecontext.New()
+	return ValidateFilesWithOptions(schemaPath, configPaths, DefaultOptions())
+}
 
-	schema, err := loadSchema(ctx, schemaPath)
-	if err != nil {
-		return createSchemaErrorResults(configPaths, err)
+// ValidateFilesWithOptions validates multiple config files against a CUE schema
+// and returns the results in the same order as configPaths. When validation
+// stops early because of FailFast or MaxErrors, fewer results are returned.
+func ValidateFilesWithOptions(schemaPath string, configPaths []string, opts Options) []ValidationResult {
+	collector := &resultCollector{}
+	Run(schemaPath, configPaths, opts, collector)
+	return collector.results
+}
+
+// Run validates config files against a CUE schema and passes every result to
+// reporter as soon as it and all files before it have been validated
+func Run(schemaPath string, configPaths []string, opts Options, reporter Reporter) Summary {
+	checks := checkOptions{concreteness: opts.Concreteness, closedness: opts.Closedness, env: opts.Env, merge: opts.MergeMode, data: opts.Data, tags: opts.Tags, values: opts.Values}
+	readFile := os.ReadFile
+	if opts.Staged {
+		readFile = readStagedFile
 	}
+	collection := newCollection(schemaPath, checks, opts.Merge, readFile)
+	collection.addContext(opts.Context)
 
-	var results []ValidationResult
-	for _, configPath := range configPaths {
-		result := validateFile(ctx, schema, configPath)
-		results = append(results, result)
+	summary := Summary{Total: len(configPaths)}
+	if len(opts.Merge) > 0 {
+		summary.Total++
 	}
+	if collection != nil {
+		summary.Total++
+	}
+	limiter := &resultLimiter{failFast: opts.FailFast, maxErrors: opts.MaxErrors}
+
+	// The cache is best effort: without it every file is simply validated
+	cache, _ := newResultCache(opts.CacheDir, schemaPath, checks)
+	newWorker := func() *validator {
+		v := newValidator(schemaPath, checks, cache)
+		v.readFile = readFile
+		return v
+	}
+
+	more := true
+	emit := func(result ValidationResult) bool {
+		collection.add(result)
+		if opts.RecordBaseline != nil {
+			opts.RecordBaseline.Add(result)
+		}
+		if opts.Baseline != nil {
+			result = opts.Baseline.Filter(result)
+		}
 
-	return results
+		result, more = limiter.apply(result)
+		summary.add(result)
+		reporter.OnResult(result)
+		return more
+	}
+
+	reporter.OnStart(summary.Total)
+	validateConcurrently(configPaths, opts.Jobs, newWorker, emit)
+	if len(opts.Merge) > 0 && more {
+		more = emit(newWorker().validateMerged(mergedName(opts.Merge), opts.Merge))
+	}
+	if collection != nil && more {
+		emit(collection.validate())
+	}
+	reporter.OnFinish(summary)
+
+	return summary
+}
+
+// resultLimiter enforces the FailFast and MaxErrors options on results in input order
+type resultLimiter struct {
+	failFast    bool
+	maxErrors   int
+	totalErrors int
 }
 
-// createSchemaErrorResults creates error results for all files when schema loading fails
-func createSchemaErrorResults(configPaths []string, err error) []ValidationResult {
-	var results []ValidationResult
-	errorMsg := fmt.Sprintf("failed to load schema: %v", err)
+// apply truncates the errors of a result to the remaining error budget and
+// reports whether validation should continue with the next file
+func (l *resultLimiter) apply(result ValidationResult) (ValidationResult, bool) {
+	more := true
+
+	if l.maxErrors > 0 {
+		remaining := l.maxErrors - l.totalErrors
+		if len(result.Errors) > remaining {
+			result.OmittedErrors = len(result.Errors) - remaining
+			result.Errors = result.Errors[:remaining]
+		}
+		l.totalErrors += len(result.Errors)
+		more = l.totalErrors < l.maxErrors
+	}
 
-	for _, path := range configPaths {
-		results = append(results, ValidationResult{
-			FileName: path,
-			IsValid:  false,
-			Errors: []ValidationError{
-				{Line: 0, Field: "", Problem: errorMsg},
-			},
-		})
+	if l.failFast && !result.IsValid {
+		more = false
 	}
-	return results
+
+	return result, more
 }
 
-// loadSchema loads and compiles a CUE schema file
-func loadSchema(ctx *cue.Context, schemaPath string) (cue.Value, error) {
-	schemaData, err := os.ReadFile(schemaPath)
-	if err != nil {
-		return cue.Value{}, fmt.Errorf("reading schema file: %w", err)
+// checkOptions are the options that change the result of validating a file
+type checkOptions struct {
+	concreteness Concreteness
+	closedness   Closedness
+	env          EnvMode
+	merge        MergeMode
+	data         []string // The cache also hashes the content of the data and values files
+	tags         []string
+	values       []string
+}
+
+// validator validates config files against a schema compiled in its own CUE context.
+// A cue.Context is not safe for concurrent use, so every worker owns a validator.
+// The schema is only compiled once the first file misses the cache.
+type validator struct {
+	schemaPath string
+	checks     checkOptions
+	cache      *resultCache
+	readFile   func(name string) ([]byte, error) // Reads config files from the working tree or the git index
+
+	ctx          *cue.Context
+	schema       cue.Value
+	schemaErr    error
+	schemaLoaded bool
+}
+
+// newValidator creates a validator for the given schema
+func newValidator(schemaPath string, checks checkOptions, cache *resultCache) *validator {
+	return &validator{
+		schemaPath: schemaPath,
+		checks:     checks,
+		cache:      cache,
+		readFile:   os.ReadFile,
+		ctx:        cuecontext.New(),
 	}
+}
 
-	schema := ctx.CompileBytes(schemaData, cue.Filename(schemaPath))
-	if schema.Err() != nil {
-		return cue.Value{}, fmt.Errorf("compiling schema: %w", schema.Err())
+// validate validates a single config file, reporting schema errors against the file
+func (v *validator) validate(configPath string) ValidationResult {
+	return v.validateMerged(configPath, []string{configPath})
+}
+
+// validateMerged validates config files merged in order as a single config called name
+func (v *validator) validateMerged(name string, configPaths []string) ValidationResult {
+	files := make([]configFile, len(configPaths))
+	for i, configPath := range configPaths {
+		configData, err := v.readFile(configPath)
+		if err != nil {
+			return createErrorResult(name, CodeReadFailure, fmt.Sprintf("failed to read file: %v", err))
+		}
+		files[i] = configFile{path: configPath, data: configData}
 	}
 
-	return schema, nil
+	key := v.cache.key(v.checks.env.cacheFiles(files)...)
+	if result, ok := v.cache.get(key); ok {
+		return result
+	}
+
+	if err := v.ensureSchema(); err != nil {
+		return createErrorResult(name, CodeSchemaLoad, fmt.Sprintf("failed to load schema: %v", err))
+	}
+
+	result := validateMergedConfig(v.ctx, v.schema, v.checks, name, files)
+	v.cache.put(key, result)
+	return result
 }
 
-// validateFile validates a single config file against the schema
-func validateFile(ctx *cue.Context, schema cue.Value, configPath string) ValidationResult {
-	configData, err := os.ReadFile(configPath)
-	if err != nil {
-		return createErrorResult(configPath, fmt.Sprintf("failed to read file: %v", err))
+// mergedName returns the name of the result for merged config files
+func mergedName(configPaths []string) string {
+	return strings.Join(configPaths, " + ")
+}
+
+// ensureSchema compiles the schema on first use
+func (v *validator) ensureSchema() error {
+	if !v.schemaLoaded {
+		v.schema, v.schemaErr = loadSchema(v.ctx, v.schemaPath, v.checks)
+		v.schemaLoaded = true
 	}
+	return v.schemaErr
+}
 
-	config, err := parseConfigFile(ctx, configPath, configData)
-	if err != nil {
-		return createErrorResult(configPath, err.Error())
+// loadSchema loads and compiles a CUE schema file with the data files and tags of checks
+func loadSchema(ctx *cue.Context, schemaPath string, checks checkOptions) (cue.Value, error) {
+	var schema cue.Value
+	if len(checks.tags) > 0 {
+		var err error
+		if schema, err = buildSchemaWithTags(ctx, schemaPath, checks.tags); err != nil {
+			return cue.Value{}, err
+		}
+	} else {
+		schemaData, err := os.ReadFile(schemaPath)
+		if err != nil {
+			return cue.Value{}, fmt.Errorf("reading schema file: %w", err)
+		}
+
+		schema = ctx.CompileBytes(schemaData, cue.Filename(schemaPath))
+		if schema.Err() != nil {
+			return cue.Value{}, fmt.Errorf("compiling schema: %w", schema.Err())
+		}
+	}
+
+	return injectData(ctx, schema, checks.data)
+}
+
+// configFile is the content of a config file
+type configFile struct {
+	path string
+	data []byte
+}
+
+// preparedConfig is the data of a config file as it is parsed
+type preparedConfig struct {
+	data     []byte
+	lines    []int             // Template line of every line of data, nil unless the file is a template
+	problems []ValidationError // Unset environment variables
+}
+
+// prepareConfig renders templates and expands placeholders as configured by checks
+func prepareConfig(ctx *cue.Context, checks checkOptions, configPath string, configData []byte) (preparedConfig, error) {
+	prepared := preparedConfig{data: configData}
+	if isTemplateFile(configPath) {
+		rendered, err := renderTemplate(ctx, configPath, configData, checks.values)
+		if err != nil {
+			return preparedConfig{}, err
+		}
+		prepared.data, prepared.lines = rendered, mapRenderedLines(configData, rendered)
 	}
+	prepared.data, prepared.problems = checks.env.prepare(configPath, prepared.data)
+	return prepared, nil
+}
+
+// validateConfig validates the contents of a single config file against the schema
+func validateConfig(ctx *cue.Context, schema cue.Value, checks checkOptions, configPath string, configData []byte) ValidationResult {
+	return validateMergedConfig(ctx, schema, checks, configPath, []configFile{{path: configPath, data: configData}})
+}
 
-	if config.Err() != nil {
-		return createValidationErrorResult(configPath, config.Err())
+// validateMergedConfig validates config files merged in order as a single
+// config called name. Errors of a merged config refer to the file they are in.
+func validateMergedConfig(ctx *cue.Context, schema cue.Value, checks checkOptions, name string, files []configFile) ValidationResult {
+	var config cue.Value
+	var envProblems []ValidationError
+	lineMaps := make(map[string][]int)
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.path
+
+		prepared, err := prepareConfig(ctx, checks, file.path, file.data)
+		if err != nil {
+			return withMergedFiles(templateErrorResult(name, file.path, err), files)
+		}
+		envProblems = append(envProblems, prepared.problems...)
+		if prepared.lines != nil {
+			lineMaps[file.path] = prepared.lines
+		}
+
+		parsed, err := parseConfigFile(ctx, file.path, prepared.data)
+		if err != nil {
+			code := CodeParseError
+			if !isSupportedFile(file.path) && !isTemplateFile(file.path) {
+				code = CodeUnsupportedFormat
+			}
+			result := createErrorResult(name, code, err.Error())
+			result.Errors[0].File = file.path
+			return withMergedFiles(result, files)
+		}
+
+		if parsed.Err() != nil {
+			vc := validationContext{fileName: name, files: []string{file.path}}
+			result := createValidationErrorResult(vc, parsed.Err())
+			for i := range result.Errors {
+				result.Errors[i].Code = CodeParseError
+			}
+			result.Errors = mapTemplateLines(result.Errors, lineMaps, []string{file.path})
+			return withMergedFiles(result, files)
+		}
+
+		if i == 0 {
+			config = parsed
+		} else {
+			config = checks.merge.mergeConfig(config, parsed)
+		}
 	}
 
 	configDef := schema.LookupPath(cue.ParsePath("#Config"))
 	if !configDef.Exists() {
-		return createErrorResult(configPath, "schema does not define #Config")
+		return createErrorResult(name, CodeSchemaNoConfig, "schema does not define #Config")
 	}
 
-	unified := configDef.Unify(config)
+	var removed, unknown [][]cue.Selector
+	if checks.env == EnvPlaceholder {
+		removed = findPlaceholderFields(config, configDef)
+	}
+	if checks.closedness == ClosednessOpen {
+		unknown = findUnknownFields(config, configDef)
+		removed = append(removed, unknown...)
+	}
+	unified := configDef.Unify(withoutFields(config, removed))
 
-	err = unified.Validate(cue.Concrete(true))
-	if err != nil {
-		return createValidationErrorResult(configPath, err)
+	vc := validationContext{fileName: name, files: paths, config: config, definition: configDef, unified: unified}
+
+	validationErrors := validateFields(vc, checks, removed)
+	if checks.concreteness == ConcretenessStrict {
+		validationErrors = append(validationErrors, findDefaultedFields(config, unified)...)
+	}
+	switch checks.closedness {
+	case ClosednessOpen:
+		validationErrors = append(downgradeUnknownFields(validationErrors), unknownFieldWarnings(config, configDef, unknown)...)
+	case ClosednessClosed:
+		validationErrors = append(validationErrors, findUndeclaredFields(config, configDef)...)
 	}
+	validationErrors = append(validationErrors, findDeprecatedFields(config, unified)...)
+	validationErrors = append(validationErrors, envProblems...)
+	validationErrors = mapTemplateLines(validationErrors, lineMaps, paths)
+
+	var suppressions []*suppression
+	var problems []ValidationError
+	for _, file := range files {
+		fileSuppressions, fileProblems := parseSuppressions(file.path, file.data)
+		suppressions = append(suppressions, fileSuppressions...)
+		problems = append(problems, fileProblems...)
+	}
+	validationErrors = applySuppressions(validationErrors, suppressions)
+	return withMergedFiles(createResult(name, append(validationErrors, problems...)), files)
+}
 
-	return ValidationResult{
-		FileName: configPath,
-		IsValid:  true,
-		Errors:   []ValidationError{},
+// withMergedFiles removes the file of every error unless the result is for
+// a merged config, where the file is needed to locate the error
+func withMergedFiles(result ValidationResult, files []configFile) ValidationResult {
+	if len(files) == 1 {
+		for i := range result.Errors {
+			result.Errors[i].File = ""
+		}
 	}
+	return result
 }
 
 // parseConfigFile parses a config file based on its extension
 func parseConfigFile(ctx *cue.Context, configPath string, configData []byte) (cue.Value, error) {
-	ext := strings.ToLower(filepath.Ext(configPath))
+	ext := configExtension(configPath)
 
 	switch ext {
 	case ".yaml", ".yml":
 		return parseYAML(ctx, configPath, configData)
 	case ".json":
 		return parseJSON(ctx, configPath, configData)
+	case ".jsonc":
+		return parseJSON(ctx, configPath, stripJSONComments(configData))
 	default:
-		return cue.Value{}, fmt.Errorf("unsupported file format: %s (supported: .yaml, .yml, .json)", ext)
+		return cue.Value{}, fmt.Errorf("unsupported file format: %s (supported: %s)",
+			ext, strings.Join(supportedExtensions, ", "))
 	}
 }
 
@@ -144,62 +441,237 @@ func parseJSON(ctx *cue.Context, configPath string, configData []byte) (cue.Valu
 	return ctx.BuildExpr(expr), nil
 }
 
+// stripJSONComments replaces // and /* */ comments outside of strings with
+// spaces, keeping line breaks so that positions stay the same
+func stripJSONComments(data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+
+	inString := false
+	for i := 0; i < len(out); i++ {
+		switch {
+		case inString:
+			if out[i] == '\\' {
+				i++
+			} else if out[i] == '"' {
+				inString = false
+			}
+		case out[i] == '"':
+			inString = true
+		case out[i] == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case out[i] == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := len(out)
+			if idx := strings.Index(string(out[i+2:]), "*/"); idx >= 0 {
+				end = i + 2 + idx + 2
+			}
+			for ; i < end; i++ {
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+			i--
+		}
+	}
+
+	return out
+}
+
+// createResult creates a result that is valid when there are no errors of
+// error severity. Errors without a severity are treated as errors.
+func createResult(fileName string, errors []ValidationError) ValidationResult {
+	if errors == nil {
+		errors = []ValidationError{}
+	}
+
+	isValid := true
+	for i := range errors {
+		if errors[i].Severity == "" {
+			errors[i].Severity = SeverityError
+		}
+		if !errors[i].IsWarning() {
+			isValid = false
+		}
+	}
+
+	return ValidationResult{
+		FileName: fileName,
+		IsValid:  isValid,
+		Errors:   errors,
+	}
+}
+
 // createErrorResult creates a single error result
-func createErrorResult(fileName string, problem string) ValidationResult {
+func createErrorResult(fileName string, code Code, problem string) ValidationResult {
 	return ValidationResult{
 		FileName: fileName,
 		IsValid:  false,
 		Errors: []ValidationError{
-			{Line: 0, Field: "", Problem: problem},
+			{Line: 0, Field: "", Problem: problem, Code: code, Severity: SeverityError},
 		},
 	}
 }
 
 // createValidationErrorResult creates a result with extracted validation errors
-func createValidationErrorResult(fileName string, err error) ValidationResult {
-	return ValidationResult{
-		FileName: fileName,
-		IsValid:  false,
-		Errors:   extractValidationErrors(err),
-	}
+func createValidationErrorResult(vc validationContext, err error) ValidationResult {
+	return createResult(vc.fileName, extractValidationErrors(err, vc))
+}
+
+// validationContext holds what is known about the config file errors are extracted for
+type validationContext struct {
+	fileName   string    // Name of the result
+	files      []string  // Config files the config was parsed from
+	config     cue.Value // The parsed config, zero if unavailable
+	definition cue.Value // The #Config definition, zero if unavailable
+	unified    cue.Value // Config unified with #Config, zero if unavailable
 }
 
 // extractValidationErrors extracts structured error information from CUE errors
-func extractValidationErrors(err error) []ValidationError {
+func extractValidationErrors(err error, vc validationContext) []ValidationError {
 	cueErrors := errors.Errors(err)
 	if len(cueErrors) == 0 {
 		return []ValidationError{
-			{Line: 0, Field: "", Problem: err.Error()},
+			{Line: 0, Field: "", Problem: err.Error(), Code: CodeValidationFailure, Severity: SeverityError},
 		}
 	}
 
 	var validationErrors []ValidationError
-	for _, e := range cueErrors {
-		ve := extractSingleError(e)
+	for i := 0; i < len(cueErrors); i++ {
+		e := cueErrors[i]
+		if isEmptyDisjunction(e) && vc.definition.Exists() {
+			details := disjunctionDetails(e, cueErrors[i+1:])
+			validationErrors = append(validationErrors, summarizeDisjunction(e, details, vc))
+			i += len(details)
+			continue
+		}
+
+		ve := extractSingleError(e, vc)
 		validationErrors = append(validationErrors, ve)
 	}
 
-	return validationErrors
+	return mergeCustomMessages(validationErrors)
 }
 
 // extractSingleError extracts information from a single CUE error
-func extractSingleError(e errors.Error) ValidationError {
-	return ValidationError{
-		Line:    extractLineNumber(e),
-		Field:   extractFieldPath(e),
-		Problem: e.Error(),
+func extractSingleError(e errors.Error, vc validationContext) ValidationError {
+	attrs := lookupFieldAttributes(vc.unified, valuePath(e.Path()))
+	pos := extractPosition(e, vc.files)
+
+	ve := ValidationError{
+		File:     pos.Filename(),
+		Line:     pos.Line(),
+		Field:    extractFieldPath(e),
+		Problem:  e.Error(),
+		Code:     classifyError(e),
+		Severity: attrs.severity,
+	}
+	if others := otherPositions(e, vc.files, pos); len(others) > 0 {
+		ve.Problem += " (also set in " + strings.Join(others, ", ") + ")"
+	}
+	if ve.Code == CodeMissingField && ve.Line == 0 {
+		describeMissingField(&ve, e, vc)
+	}
+	if path := valuePath(e.Path()).Selectors(); ve.Line == 0 && len(path) > 0 && vc.config.Exists() {
+		// Values of a config rebuilt by withoutFields have no label positions
+		pos := enclosingPos(vc.config, path)
+		ve.File, ve.Line = pos.Filename(), pos.Line()
+	}
+	if isFieldNotAllowed(e) && vc.definition.Exists() {
+		if hint := fieldSuggestion(vc.definition, e.Path()); hint != "" {
+			ve.Problem += "; " + hint
+		}
+	}
+	if attrs.msg != "" {
+		ve.Detail = ve.Problem
+		ve.Problem = attrs.msg
+	}
+	return ve
+}
+
+// describeMissingField rephrases an error for a field that is absent from the
+// config and attributes it to the line of the enclosing struct, as there is no
+// node in the config for the field itself
+func describeMissingField(ve *ValidationError, e errors.Error, vc validationContext) {
+	path := valuePath(e.Path()).Selectors()
+	if len(path) == 0 || path[len(path)-1].LabelType() != cue.StringLabel {
+		return
+	}
+	if !vc.config.Exists() || vc.config.LookupPath(cue.MakePath(path...)).Exists() {
+		return
+	}
+
+	parent := path[:len(path)-1]
+	ve.Problem = fmt.Sprintf("missing required field `%s`", path[len(path)-1].Unquoted())
+	if len(parent) > 0 {
+		ve.Problem += fmt.Sprintf(" in `%s`", selectorsToField(parent))
+	}
+
+	pos := enclosingPos(vc.config, parent)
+	ve.File, ve.Line = pos.Filename(), pos.Line()
+}
+
+// enclosingPos returns the position of the value at path in config, or of its
+// closest ancestor with a position when the value is not set in the config
+func enclosingPos(config cue.Value, path []cue.Selector) token.Pos {
+	for i := len(path); i >= 0; i-- {
+		v := config.LookupPath(cue.MakePath(path[:i]...))
+		if pos := v.Pos(); v.Exists() && pos.Line() > 0 {
+			return pos
+		}
+	}
+	return token.NoPos
+}
+
+// mergeCustomMessages merges consecutive errors of the same field that share a
+// custom message, so that a message is not repeated for every CUE error
+func mergeCustomMessages(validationErrors []ValidationError) []ValidationError {
+	var merged []ValidationError
+	for _, ve := range validationErrors {
+		if n := len(merged); n > 0 && ve.Detail != "" {
+			prev := &merged[n-1]
+			if prev.Detail != "" && prev.Field == ve.Field && prev.Problem == ve.Problem {
+				separator := "; "
+				if strings.HasSuffix(prev.Detail, ":") {
+					separator = " "
+				}
+				prev.Detail += separator + ve.Detail
+				if prev.Line == 0 {
+					prev.File, prev.Line = ve.File, ve.Line
+				}
+				continue
+			}
+		}
+		merged = append(merged, ve)
+	}
+	return merged
+}
+
+// extractPosition returns the error position in the config files. When the
+// config is merged from several files, the position in the last file wins
+// because overlays usually introduce the error. Positions in the schema are
+// skipped because they would be reported as if they were lines of the config.
+func extractPosition(e errors.Error, files []string) token.Pos {
+	best, bestIndex := token.NoPos, -1
+	for _, pos := range errors.Positions(e) {
+		if index := slices.Index(files, pos.Filename()); pos.Line() > 0 && index > bestIndex {
+			best, bestIndex = pos, index
+		}
 	}
+	return best
 }
 
-// extractLineNumber extracts the line number from error positions
-func extractLineNumber(e errors.Error) int {
-	positions := errors.Positions(e)
-	for _, pos := range positions {
-		if line := pos.Line(); line > 0 {
-			return line
+// otherPositions formats the positions of an error in the config files other
+// than primary, so that every file contributing to an error can be found
+func otherPositions(e errors.Error, files []string, primary token.Pos) []string {
+	var others []string
+	for _, pos := range errors.Positions(e) {
+		if pos.Line() > 0 && slices.Contains(files, pos.Filename()) && pos.Filename() != primary.Filename() {
+			others = append(others, fmt.Sprintf("%s:%d", pos.Filename(), pos.Line()))
 		}
 	}
-	return 0
+	return others
 }
 
 // extractFieldPath extracts and formats the field path from error
@@ -230,5 +702,6 @@ func formatPath(path []string) string {
 func isValidPathElement(p string) bool {
 	return p != "" &&
 		!strings.HasPrefix(p, "[") &&
-		p != "#Config"
+		p != "#Config" &&
+		p != collectionDefinition
 }
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -62,7 +65,7 @@ name: "my-service"
 `,
 			},
 			wantValid:  false,
-			wantErrors: []string{"version", "incomplete"},
+			wantErrors: []string{"version", "missing required field"},
 		},
 		{
 			name: "missing required field JSON",
@@ -76,7 +79,7 @@ name: "my-service"
 				"config.json": `{"name": "my-service"}`,
 			},
 			wantValid:  false,
-			wantErrors: []string{"version", "incomplete"},
+			wantErrors: []string{"version", "missing required field"},
 		},
 		{
 			name: "value out of range YAML",
@@ -244,6 +247,37 @@ environment: "testing"
 				"environment",
 			},
 		},
+		{
+			name: "misspelled field YAML",
+			schema: `
+				#Config: {
+					name: string
+					healthCheck?: {path?: string}
+				}
+			`,
+			configs: map[string]string{
+				"config.yaml": `
+name: "my-service"
+helthCheck:
+  pth: /health
+`,
+			},
+			wantValid:  false,
+			wantErrors: []string{"did you mean `healthCheck`?"},
+		},
+		{
+			name: "misspelled nested field JSON",
+			schema: `
+				#Config: {
+					ports?: [...{port: int, protocol?: string}]
+				}
+			`,
+			configs: map[string]string{
+				"config.json": `{"ports": [{"port": 80, "protcol": "TCP"}]}`,
+			},
+			wantValid:  false,
+			wantErrors: []string{"ports.0.protcol", "did you mean `protocol`?"},
+		},
 		{
 			name: "multiple files mixed formats",
 			schema: `
@@ -435,3 +469,1727 @@ func TestValidateFilesWithUnsupportedFormat(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateFilesWithJobs(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	schemaPath := filepath.Join(tmpDir, "schema.cue")
+	if err := os.WriteFile(schemaPath, []byte(`#Config: {replicas: int & >=1}`), 0644); err != nil {
+		t.Fatalf("failed to write schema file: %v", err)
+	}
+
+	// Every third file is invalid so that the order of results can be checked
+	var configPaths []string
+	for i := range 30 {
+		content := fmt.Sprintf("replicas: %d", i%3)
+		configPath := filepath.Join(tmpDir, fmt.Sprintf("config%02d.yaml", i))
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+		configPaths = append(configPaths, configPath)
+	}
+
+	for _, jobs := range []int{0, 1, 4, 100} {
+		t.Run(fmt.Sprintf("jobs=%d", jobs), func(t *testing.T) {
+			results := ValidateFilesWithOptions(schemaPath, configPaths, Options{Jobs: jobs})
+
+			if len(results) != len(configPaths) {
+				t.Fatalf("expected %d results, got %d", len(configPaths), len(results))
+			}
+
+			for i, result := range results {
+				if result.FileName != configPaths[i] {
+					t.Errorf("result %d: FileName = %s, want %s", i, result.FileName, configPaths[i])
+				}
+				if wantValid := i%3 != 0; result.IsValid != wantValid {
+					t.Errorf("result %d: IsValid = %v, want %v", i, result.IsValid, wantValid)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithLimits(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	schemaPath := filepath.Join(tmpDir, "schema.cue")
+	if err := os.WriteFile(schemaPath, []byte(`#Config: {a: int, b: int, c: int}`), 0644); err != nil {
+		t.Fatalf("failed to write schema file: %v", err)
+	}
+
+	// Files with 0, 3, 0 and 3 type errors
+	contents := []string{
+		`{"a": 1, "b": 2, "c": 3}`,
+		`{"a": "x", "b": "y", "c": "z"}`,
+		`{"a": 1, "b": 2, "c": 3}`,
+		`{"a": "x", "b": "y", "c": "z"}`,
+	}
+	var configPaths []string
+	for i, content := range contents {
+		configPath := filepath.Join(tmpDir, fmt.Sprintf("config%d.json", i))
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+		configPaths = append(configPaths, configPath)
+	}
+
+	tests := []struct {
+		name        string
+		opts        Options
+		wantResults int
+		wantErrors  []int // Reported errors per result
+		wantOmitted []int // Omitted errors per result
+	}{
+		{
+			name:        "no limits",
+			opts:        Options{Jobs: 2},
+			wantResults: 4,
+			wantErrors:  []int{0, 3, 0, 3},
+			wantOmitted: []int{0, 0, 0, 0},
+		},
+		{
+			name:        "fail fast",
+			opts:        Options{Jobs: 2, FailFast: true},
+			wantResults: 2,
+			wantErrors:  []int{0, 3},
+			wantOmitted: []int{0, 0},
+		},
+		{
+			name:        "max errors within a file",
+			opts:        Options{Jobs: 2, MaxErrors: 2},
+			wantResults: 2,
+			wantErrors:  []int{0, 2},
+			wantOmitted: []int{0, 1},
+		},
+		{
+			name:        "max errors across files",
+			opts:        Options{Jobs: 2, MaxErrors: 4},
+			wantResults: 4,
+			wantErrors:  []int{0, 3, 0, 1},
+			wantOmitted: []int{0, 0, 0, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := ValidateFilesWithOptions(schemaPath, configPaths, tt.opts)
+
+			if len(results) != tt.wantResults {
+				t.Fatalf("expected %d results, got %d", tt.wantResults, len(results))
+			}
+
+			for i, result := range results {
+				if len(result.Errors) != tt.wantErrors[i] {
+					t.Errorf("result %d: got %d errors, want %d", i, len(result.Errors), tt.wantErrors[i])
+				}
+				if result.OmittedErrors != tt.wantOmitted[i] {
+					t.Errorf("result %d: OmittedErrors = %d, want %d", i, result.OmittedErrors, tt.wantOmitted[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	cacheDir := filepath.Join(tmpDir, "cache")
+
+	schemaPath := filepath.Join(tmpDir, "schema.cue")
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(`replicas: 3`), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	countEntries := func() int {
+		entries, _ := filepath.Glob(filepath.Join(cacheDir, "*", "*.json"))
+		return len(entries)
+	}
+
+	// Mark every cached result, so that results read from the cache can be told apart
+	const marker = "read from cache"
+	markEntries := func() {
+		entries, _ := filepath.Glob(filepath.Join(cacheDir, "*", "*.json"))
+		for _, entry := range entries {
+			data, err := os.ReadFile(entry)
+			if err != nil {
+				t.Fatalf("failed to read cache entry: %v", err)
+			}
+			var result ValidationResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				t.Fatalf("failed to parse cache entry: %v", err)
+			}
+			result.Errors = append(result.Errors, ValidationError{Problem: marker, Severity: SeverityWarning})
+			data, _ = json.Marshal(result)
+			if err := os.WriteFile(entry, data, 0644); err != nil {
+				t.Fatalf("failed to write cache entry: %v", err)
+			}
+		}
+	}
+
+	steps := []struct {
+		name        string
+		schema      string
+		wantValid   bool
+		wantEntries int
+		wantCached  bool
+	}{
+		{"first run", `#Config: {replicas: int & <=5}`, true, 1, false},
+		{"unchanged", `#Config: {replicas: int & <=5}`, true, 1, true},
+		{"schema changed", `#Config: {replicas: int & <=2}`, false, 2, false},
+	}
+
+	for _, step := range steps {
+		if err := os.WriteFile(schemaPath, []byte(step.schema), 0644); err != nil {
+			t.Fatalf("failed to write schema file: %v", err)
+		}
+
+		results := ValidateFilesWithOptions(schemaPath, []string{configPath}, Options{CacheDir: cacheDir})
+
+		if results[0].IsValid != step.wantValid {
+			t.Errorf("%s: IsValid = %v, want %v", step.name, results[0].IsValid, step.wantValid)
+		}
+		if got := countEntries(); got != step.wantEntries {
+			t.Errorf("%s: got %d cache entries, want %d", step.name, got, step.wantEntries)
+		}
+		cached := slices.ContainsFunc(results[0].Errors, func(e ValidationError) bool { return e.Problem == marker })
+		if cached != step.wantCached {
+			t.Errorf("%s: read from cache = %v, want %v", step.name, cached, step.wantCached)
+		}
+		markEntries()
+	}
+}
+
+func TestValidateFilesWithSuppressions(t *testing.T) {
+	schema := `
+		#Config: {
+			name: string & =~"^[a-z][a-z0-9-]*$"
+			replicas: int & >=1
+			resources: {
+				cpu: string & =~"^[0-9]+m?$"
+			}
+		}
+	`
+
+	tests := []struct {
+		name       string
+		filename   string
+		content    string
+		wantValid  bool
+		wantErrors []string // Expected error message fragments
+	}{
+		{
+			name:     "ignore next line YAML",
+			filename: "config.yaml",
+			content: `
+# cint:ignore-next-line reason="legacy name"
+name: MyService
+replicas: 1
+resources:
+  cpu: 100m
+`,
+			wantValid: true,
+		},
+		{
+			name:     "ignore field YAML",
+			filename: "config.yaml",
+			content: `
+# cint:ignore field=resources reason="sanctioned exception"
+name: my-service
+replicas: 1
+resources:
+  cpu: 100MB
+`,
+			wantValid: true,
+		},
+		{
+			name:     "ignore next line with other field YAML",
+			filename: "config.yaml",
+			content: `
+name: my-service
+# cint:ignore-next-line field=name
+replicas: 0
+resources:
+  cpu: 100m
+`,
+			wantValid:  false,
+			wantErrors: []string{"replicas", "unused suppression"},
+		},
+		{
+			name:     "unused suppression YAML",
+			filename: "config.yaml",
+			content: `
+name: my-service
+# cint:ignore-next-line
+replicas: 1
+resources:
+  cpu: 100m
+`,
+			wantValid:  false,
+			wantErrors: []string{"unused suppression"},
+		},
+		{
+			name:     "suppression does not hide missing fields YAML",
+			filename: "config.yaml",
+			content: `
+# cint:ignore-next-line field=replicas
+replicas: 0
+resources: {}
+`,
+			wantValid:  false,
+			wantErrors: []string{"missing required field `name`", "missing required field `cpu` in `resources`"},
+		},
+		{
+			name:     "malformed suppression YAML",
+			filename: "config.yaml",
+			content: `
+# cint:ignore reason="no field given"
+name: my-service
+replicas: 1
+resources:
+  cpu: 100m
+`,
+			wantValid:  false,
+			wantErrors: []string{"requires a field"},
+		},
+		{
+			name:     "ignore next line JSONC",
+			filename: "config.jsonc",
+			content: `{
+  /* Replicas are scaled by the autoscaler */
+  // cint:ignore-next-line field=replicas
+  "replicas": 0,
+  "name": "my-service", // "quoted" comment
+  "resources": {"cpu": "100m"}
+}`,
+			wantValid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			schemaPath := filepath.Join(tmpDir, "schema.cue")
+			if err := os.WriteFile(schemaPath, []byte(schema), 0644); err != nil {
+				t.Fatalf("failed to write schema file: %v", err)
+			}
+
+			configPath := filepath.Join(tmpDir, tt.filename)
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			results := ValidateFiles(schemaPath, []string{configPath})
+
+			if results[0].IsValid != tt.wantValid {
+				t.Errorf("IsValid = %v, want %v (errors: %v)", results[0].IsValid, tt.wantValid, results[0].Errors)
+			}
+
+			var allErrors []string
+			for _, err := range results[0].Errors {
+				allErrors = append(allErrors, fmt.Sprintf("field=%s problem=%s", err.Field, err.Problem))
+			}
+			errorStr := strings.Join(allErrors, "; ")
+			for _, expectedError := range tt.wantErrors {
+				if !strings.Contains(errorStr, expectedError) {
+					t.Errorf("expected error containing %q in errors: %s", expectedError, errorStr)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithBaseline(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	schemaPath := filepath.Join(tmpDir, "schema.cue")
+	if err := os.WriteFile(schemaPath, []byte(`#Config: {name: string, replicas: int & <=5}`), 0644); err != nil {
+		t.Fatalf("failed to write schema file: %v", err)
+	}
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	writeConfig := func(content string) {
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+	}
+
+	// Record the existing violation
+	writeConfig("name: 1\nreplicas: 3\n")
+	recorded := NewBaseline()
+	ValidateFilesWithOptions(schemaPath, []string{configPath}, Options{RecordBaseline: recorded})
+
+	baselinePath := filepath.Join(tmpDir, "baseline.json")
+	if err := recorded.Write(baselinePath); err != nil {
+		t.Fatalf("failed to write baseline: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		content    string
+		wantValid  bool
+		wantErrors []string // Expected error message fragments
+	}{
+		{
+			name:      "known error",
+			content:   "name: 1\nreplicas: 3\n",
+			wantValid: true,
+		},
+		{
+			name:      "known error on another line",
+			content:   "replicas: 3\n\nname: 1\n",
+			wantValid: true,
+		},
+		{
+			name:       "new error",
+			content:    "name: 1\nreplicas: 8\n",
+			wantValid:  false,
+			wantErrors: []string{"replicas"},
+		},
+		{
+			name:       "new missing field",
+			content:    "name: 1\n",
+			wantValid:  false,
+			wantErrors: []string{"replicas"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeConfig(tt.content)
+
+			baseline, err := LoadBaseline(baselinePath)
+			if err != nil {
+				t.Fatalf("failed to load baseline: %v", err)
+			}
+			results := ValidateFilesWithOptions(schemaPath, []string{configPath}, Options{Baseline: baseline})
+
+			if results[0].IsValid != tt.wantValid {
+				t.Errorf("IsValid = %v, want %v (errors: %v)", results[0].IsValid, tt.wantValid, results[0].Errors)
+			}
+			for i, expectedError := range tt.wantErrors {
+				if i >= len(results[0].Errors) || !strings.Contains(results[0].Errors[i].Field, expectedError) {
+					t.Errorf("expected error for field %q, got: %v", expectedError, results[0].Errors)
+				}
+			}
+			if len(results[0].Errors) != len(tt.wantErrors) {
+				t.Errorf("got %d errors, want %d: %v", len(results[0].Errors), len(tt.wantErrors), results[0].Errors)
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithWarnings(t *testing.T) {
+	schema := `
+		#Config: {
+			name: string
+			replicas: int & >=2 @cint(severity=warning)
+			ports?: [...{port: int & <1024 @cint(severity=warning)}]
+			resources?: {cpu: string, memory: string}
+		}
+	`
+
+	tests := []struct {
+		name         string
+		content      string
+		wantValid    bool
+		wantSeverity map[string]Severity // field -> severity
+	}{
+		{
+			name:         "warning only",
+			content:      "name: web\nreplicas: 1\n",
+			wantValid:    true,
+			wantSeverity: map[string]Severity{"replicas": SeverityWarning},
+		},
+		{
+			name:         "warning in list element",
+			content:      "name: web\nreplicas: 2\nports:\n  - port: 8080\n",
+			wantValid:    true,
+			wantSeverity: map[string]Severity{"ports.0.port": SeverityWarning},
+		},
+		{
+			name:      "warning and error",
+			content:   "name: 1\nreplicas: 1\n",
+			wantValid: false,
+			wantSeverity: map[string]Severity{
+				"name":     SeverityError,
+				"replicas": SeverityWarning,
+			},
+		},
+		{
+			name:      "warning and missing fields",
+			content:   "replicas: 1\nresources:\n  cpu: \"1\"\n",
+			wantValid: false,
+			wantSeverity: map[string]Severity{
+				"replicas":         SeverityWarning,
+				"name":             SeverityError,
+				"resources.memory": SeverityError,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			schemaPath := filepath.Join(tmpDir, "schema.cue")
+			if err := os.WriteFile(schemaPath, []byte(schema), 0644); err != nil {
+				t.Fatalf("failed to write schema file: %v", err)
+			}
+
+			configPath := filepath.Join(tmpDir, "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			results := ValidateFiles(schemaPath, []string{configPath})
+
+			if results[0].IsValid != tt.wantValid {
+				t.Errorf("IsValid = %v, want %v", results[0].IsValid, tt.wantValid)
+			}
+
+			if len(results[0].Errors) != len(tt.wantSeverity) {
+				t.Fatalf("got %d errors, want %d: %v", len(results[0].Errors), len(tt.wantSeverity), results[0].Errors)
+			}
+			for _, err := range results[0].Errors {
+				if want := tt.wantSeverity[err.Field]; err.Severity != want {
+					t.Errorf("field %s: Severity = %q, want %q", err.Field, err.Severity, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithCustomMessages(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	schemaPath := filepath.Join(tmpDir, "schema.cue")
+	schema := `
+		#Config: {
+			name: string & =~"^[a-z][a-z0-9-]*$" @cint(msg="use lowercase-with-hyphens, see RUNBOOK-12")
+			environment: "development" | "production" @cint(msg="unknown environment")
+			replicas: int & >=1
+		}
+	`
+	if err := os.WriteFile(schemaPath, []byte(schema), 0644); err != nil {
+		t.Fatalf("failed to write schema file: %v", err)
+	}
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("name: MyService\nenvironment: dev\nreplicas: 0\n"), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	results := ValidateFiles(schemaPath, []string{configPath})
+
+	want := []struct {
+		field   string
+		problem string
+		detail  string // Expected fragment of the original CUE message
+	}{
+		{"name", "use lowercase-with-hyphens, see RUNBOOK-12", "out of bound"},
+		{"environment", "unknown environment", `must be one of "development", "production"`},
+		{"replicas", "#Config.replicas: invalid value 0 (out of bound >=1)", ""},
+	}
+
+	if len(results[0].Errors) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(results[0].Errors), len(want), results[0].Errors)
+	}
+	for i, w := range want {
+		err := results[0].Errors[i]
+		if err.Field != w.field || err.Problem != w.problem {
+			t.Errorf("error %d = (%s, %q), want (%s, %q)", i, err.Field, err.Problem, w.field, w.problem)
+		}
+		if !strings.Contains(err.Detail, w.detail) || (w.detail == "" && err.Detail != "") {
+			t.Errorf("error %d: Detail = %q, want it to contain %q", i, err.Detail, w.detail)
+		}
+	}
+}
+
+func TestValidateFilesWithDeprecatedFields(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	schemaPath := filepath.Join(tmpDir, "schema.cue")
+	schema := `
+		#Config: {
+			image?: string @deprecated("use spec.image instead")
+			spec?: image?: string
+			ports?: [...{
+				port: int
+				proto?: string @deprecated()
+			}]
+		}
+	`
+	if err := os.WriteFile(schemaPath, []byte(schema), 0644); err != nil {
+		t.Fatalf("failed to write schema file: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		content      string
+		wantWarnings []ValidationError
+	}{
+		{
+			name:    "new fields only",
+			content: "spec:\n  image: nginx\nports:\n  - port: 80\n",
+		},
+		{
+			name:    "deprecated fields",
+			content: "image: nginx\nports:\n  - port: 80\n    proto: tcp\n",
+			wantWarnings: []ValidationError{
+				{Line: 1, Field: "image", Problem: "field is deprecated: use spec.image instead"},
+				{Line: 4, Field: "ports.0.proto", Problem: "field is deprecated"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(tmpDir, "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			results := ValidateFiles(schemaPath, []string{configPath})
+
+			if !results[0].IsValid {
+				t.Errorf("expected deprecated fields not to fail validation: %v", results[0].Errors)
+			}
+			if len(results[0].Errors) != len(tt.wantWarnings) {
+				t.Fatalf("got %d warnings, want %d: %v", len(results[0].Errors), len(tt.wantWarnings), results[0].Errors)
+			}
+			for i, want := range tt.wantWarnings {
+				got := results[0].Errors[i]
+				if got.Line != want.Line || got.Field != want.Field || got.Problem != want.Problem || !got.IsWarning() {
+					t.Errorf("warning %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithDisjunctions(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	schemaPath := filepath.Join(tmpDir, "schema.cue")
+	schema := `
+		#Disk: {kind: "disk", size: int & <=100}
+		#Memory: {kind: "memory", limit: string}
+
+		catalog: teams: [{name: "payments"}, {name: "search"}]
+
+		#Config: {
+			environment: "development" | "staging" | "production"
+			team?: or([for t in catalog.teams {t.name}])
+			interval?: string & =~"^[0-9]+s$" | *"30s"
+			ports?: [...{protocol?: "TCP" | "UDP" | *"TCP"}]
+			storage?: #Disk | #Memory
+		}
+	`
+	if err := os.WriteFile(schemaPath, []byte(schema), 0644); err != nil {
+		t.Fatalf("failed to write schema file: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		wantField   string
+		wantProblem string // Expected fragment of the only error
+	}{
+		{
+			name:        "enum",
+			content:     "environment: dev\n",
+			wantField:   "environment",
+			wantProblem: `invalid value "dev" (must be one of "development", "staging", "production")`,
+		},
+		{
+			name:        "enum with default in list",
+			content:     "environment: staging\nports:\n  - protocol: HTTP\n",
+			wantField:   "ports.0.protocol",
+			wantProblem: `invalid value "HTTP" (must be one of "TCP", "UDP")`,
+		},
+		{
+			name:        "constraint or default",
+			content:     "environment: staging\ninterval: 30\n",
+			wantField:   "interval",
+			wantProblem: `invalid value 30 (must be one of string & =~"^[0-9]+s$", "30s")`,
+		},
+		{
+			name:        "or of comprehension",
+			content:     "environment: staging\nteam: paymnts\n",
+			wantField:   "team",
+			wantProblem: `invalid value "paymnts" (must be one of "payments", "search")`,
+		},
+		{
+			name:        "struct alternatives",
+			content:     "environment: staging\nstorage:\n  kind: disk\n  size: 500\n",
+			wantField:   "storage",
+			wantProblem: "closest is #Disk: size: invalid value 500 (out of bound <=100)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(tmpDir, "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			results := ValidateFiles(schemaPath, []string{configPath})
+
+			if len(results[0].Errors) != 1 {
+				t.Fatalf("expected a single error, got %d: %v", len(results[0].Errors), results[0].Errors)
+			}
+			err := results[0].Errors[0]
+			if err.Field != tt.wantField {
+				t.Errorf("Field = %s, want %s", err.Field, tt.wantField)
+			}
+			if !strings.Contains(err.Problem, tt.wantProblem) {
+				t.Errorf("Problem = %q, want it to contain %q", err.Problem, tt.wantProblem)
+			}
+			if err.Line == 0 {
+				t.Errorf("expected a line number for %s", err.Field)
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithCodes(t *testing.T) {
+	schema := `
+		#Config: {
+			name: string & =~"^[a-z]+$"
+			replicas: int & >=1
+			mode: "fast" | "slow"
+			version?: "v1"
+			owner?: string @deprecated()
+		}
+	`
+
+	tests := []struct {
+		name      string
+		fileName  string
+		content   string
+		wantCodes map[string]Code // field -> code
+	}{
+		{
+			name:      "type mismatch",
+			fileName:  "config.yaml",
+			content:   "name: web\nreplicas: one\nmode: fast\n",
+			wantCodes: map[string]Code{"replicas": CodeTypeMismatch},
+		},
+		{
+			name:      "out of bound and regex mismatch",
+			fileName:  "config.yaml",
+			content:   "name: Web\nreplicas: 0\nmode: fast\n",
+			wantCodes: map[string]Code{"name": CodeRegexMismatch, "replicas": CodeOutOfBound},
+		},
+		{
+			name:      "field not allowed",
+			fileName:  "config.yaml",
+			content:   "name: web\nreplicas: 1\nmode: fast\nextra: 1\n",
+			wantCodes: map[string]Code{"extra": CodeFieldNotAllowed},
+		},
+		{
+			name:      "missing required field",
+			fileName:  "config.yaml",
+			content:   "name: web\nmode: fast\n",
+			wantCodes: map[string]Code{"replicas": CodeMissingField},
+		},
+		{
+			name:      "no alternative matched",
+			fileName:  "config.yaml",
+			content:   "name: web\nreplicas: 1\nmode: medium\n",
+			wantCodes: map[string]Code{"mode": CodeNoAlternative},
+		},
+		{
+			name:      "conflicting values",
+			fileName:  "config.yaml",
+			content:   "name: web\nreplicas: 1\nmode: fast\nversion: v2\n",
+			wantCodes: map[string]Code{"version": CodeConflictingValues},
+		},
+		{
+			name:      "deprecated field",
+			fileName:  "config.yaml",
+			content:   "name: web\nreplicas: 1\nmode: fast\nowner: ops\n",
+			wantCodes: map[string]Code{"owner": CodeDeprecatedField},
+		},
+		{
+			name:      "parse error",
+			fileName:  "config.json",
+			content:   `{"name": `,
+			wantCodes: map[string]Code{"": CodeParseError},
+		},
+		{
+			name:      "unsupported format",
+			fileName:  "config.toml",
+			content:   "name = 'web'\n",
+			wantCodes: map[string]Code{"": CodeUnsupportedFormat},
+		},
+		{
+			name:      "suppressed by code",
+			fileName:  "config.yaml",
+			content:   "# cint:ignore code=CINT011\nname: web\nreplicas: 0\nmode: fast\n",
+			wantCodes: map[string]Code{},
+		},
+		{
+			name:      "suppression with other code",
+			fileName:  "config.yaml",
+			content:   "# cint:ignore-next-line code=CINT010\nreplicas: 0\nname: web\nmode: fast\n",
+			wantCodes: map[string]Code{"replicas": CodeOutOfBound, "": CodeUnusedSuppression},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			schemaPath := filepath.Join(tmpDir, "schema.cue")
+			if err := os.WriteFile(schemaPath, []byte(schema), 0644); err != nil {
+				t.Fatalf("failed to write schema file: %v", err)
+			}
+
+			configPath := filepath.Join(tmpDir, tt.fileName)
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			results := ValidateFiles(schemaPath, []string{configPath})
+
+			if len(results[0].Errors) != len(tt.wantCodes) {
+				t.Fatalf("got %d errors, want %d: %v", len(results[0].Errors), len(tt.wantCodes), results[0].Errors)
+			}
+			for _, err := range results[0].Errors {
+				if want := tt.wantCodes[err.Field]; err.Code != want {
+					t.Errorf("field %q: Code = %q, want %q (%s)", err.Field, err.Code, want, err.Problem)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithMissingFields(t *testing.T) {
+	schema := `
+		#Config: {
+			name: string
+			replicas: int
+			resources: {cpu: string, memory!: string}
+			ports?: [...{port: int, protocol: string}]
+		}
+	`
+
+	tests := []struct {
+		name        string
+		fileName    string
+		content     string
+		wantField   string
+		wantLine    int
+		wantProblem string
+	}{
+		{
+			name:        "top level",
+			fileName:    "config.yaml",
+			content:     "# service\nname: web\nresources:\n  cpu: 100m\n  memory: 1Gi\n",
+			wantField:   "replicas",
+			wantLine:    2,
+			wantProblem: "missing required field `replicas`",
+		},
+		{
+			name:        "nested struct",
+			fileName:    "config.yaml",
+			content:     "name: web\nreplicas: 1\nresources:\n  cpu: 100m\n",
+			wantField:   "resources.memory",
+			wantLine:    3,
+			wantProblem: "missing required field `memory` in `resources`",
+		},
+		{
+			name:        "list element",
+			fileName:    "config.yaml",
+			content:     "name: web\nreplicas: 1\nresources:\n  cpu: 100m\n  memory: 1Gi\nports:\n  - port: 80\n",
+			wantField:   "ports.0.protocol",
+			wantLine:    6,
+			wantProblem: "missing required field `protocol` in `ports.0`",
+		},
+		{
+			name:        "nested struct JSON",
+			fileName:    "config.json",
+			content:     "{\n  \"name\": \"web\",\n  \"replicas\": 1,\n  \"resources\": {\n    \"cpu\": \"100m\"\n  }\n}\n",
+			wantField:   "resources.memory",
+			wantLine:    4,
+			wantProblem: "missing required field `memory` in `resources`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			schemaPath := filepath.Join(tmpDir, "schema.cue")
+			if err := os.WriteFile(schemaPath, []byte(schema), 0644); err != nil {
+				t.Fatalf("failed to write schema file: %v", err)
+			}
+
+			configPath := filepath.Join(tmpDir, tt.fileName)
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			results := ValidateFiles(schemaPath, []string{configPath})
+
+			if len(results[0].Errors) != 1 {
+				t.Fatalf("got %d errors, want 1: %v", len(results[0].Errors), results[0].Errors)
+			}
+			err := results[0].Errors[0]
+			if err.Field != tt.wantField || err.Line != tt.wantLine || err.Problem != tt.wantProblem {
+				t.Errorf("got field %q line %d problem %q, want field %q line %d problem %q",
+					err.Field, err.Line, err.Problem, tt.wantField, tt.wantLine, tt.wantProblem)
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithConcreteness(t *testing.T) {
+	schema := `
+		#Config: {
+			name: string
+			replicas: int & >=1
+			mode: *"fast" | "slow"
+			resources: {cpu: *"100m" | string}
+			healthCheck?: {path: string, interval?: string | *"30s"}
+		}
+	`
+
+	tests := []struct {
+		name         string
+		concreteness Concreteness
+		content      string
+		wantCodes    map[string]Code // field -> code
+	}{
+		{
+			name:         "lenient allows missing fields",
+			concreteness: ConcretenessLenient,
+			content:      "name: web\n",
+			wantCodes:    map[string]Code{},
+		},
+		{
+			name:         "lenient still checks set fields",
+			concreteness: ConcretenessLenient,
+			content:      "name: web\nreplicas: 0\n",
+			wantCodes:    map[string]Code{"replicas": CodeOutOfBound},
+		},
+		{
+			name:         "default requires fields without defaults",
+			concreteness: ConcretenessDefault,
+			content:      "name: web\n",
+			wantCodes:    map[string]Code{"replicas": CodeMissingField},
+		},
+		{
+			name:         "empty means default",
+			concreteness: "",
+			content:      "name: web\nreplicas: 1\n",
+			wantCodes:    map[string]Code{},
+		},
+		{
+			name:         "strict flags defaults",
+			concreteness: ConcretenessStrict,
+			content:      "name: web\nreplicas: 1\n",
+			wantCodes:    map[string]Code{"mode": CodeDefaultValue, "resources.cpu": CodeDefaultValue},
+		},
+		{
+			name:         "strict with explicit values",
+			concreteness: ConcretenessStrict,
+			content:      "name: web\nreplicas: 1\nmode: fast\nresources:\n  cpu: 100m\n",
+			wantCodes:    map[string]Code{},
+		},
+		{
+			name:         "strict flags defaults of optional fields",
+			concreteness: ConcretenessStrict,
+			content:      "name: web\nreplicas: 1\nmode: fast\nresources:\n  cpu: 100m\nhealthCheck:\n  path: /x\n",
+			wantCodes:    map[string]Code{"healthCheck.interval": CodeDefaultValue},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			schemaPath := filepath.Join(tmpDir, "schema.cue")
+			if err := os.WriteFile(schemaPath, []byte(schema), 0644); err != nil {
+				t.Fatalf("failed to write schema file: %v", err)
+			}
+
+			configPath := filepath.Join(tmpDir, "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			opts := DefaultOptions()
+			opts.Concreteness = tt.concreteness
+			results := ValidateFilesWithOptions(schemaPath, []string{configPath}, opts)
+
+			if len(results[0].Errors) != len(tt.wantCodes) {
+				t.Fatalf("got %d errors, want %d: %v", len(results[0].Errors), len(tt.wantCodes), results[0].Errors)
+			}
+			for _, err := range results[0].Errors {
+				if want := tt.wantCodes[err.Field]; err.Code != want {
+					t.Errorf("field %q: Code = %q, want %q (%s)", err.Field, err.Code, want, err.Problem)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithClosedness(t *testing.T) {
+	schema := `
+		#Config: {
+			name: string
+			labels?: {team?: string, ...}
+			limits?: [string]: int
+			extra?: _
+			resources?: {cpu: string, memory: string}
+		}
+	`
+
+	tests := []struct {
+		name         string
+		closedness   Closedness
+		content      string
+		wantValid    bool
+		wantSeverity map[string]Severity // field -> severity
+		wantCodes    map[string]Code     // field -> code, CodeFieldNotAllowed if not listed
+	}{
+		{
+			name:         "open reports unknown fields as warnings",
+			closedness:   ClosednessOpen,
+			content:      "name: web\nnmae: web\n",
+			wantValid:    true,
+			wantSeverity: map[string]Severity{"nmae": SeverityWarning},
+		},
+		{
+			name:       "open still reports missing fields",
+			closedness: ClosednessOpen,
+			content:    "name: web\nhelthCheck: 1\nresources:\n  cpu: \"1\"\n",
+			wantValid:  false,
+			wantSeverity: map[string]Severity{
+				"helthCheck":       SeverityWarning,
+				"resources.memory": SeverityError,
+			},
+			wantCodes: map[string]Code{"resources.memory": CodeMissingField},
+		},
+		{
+			name:         "default enforces closed definitions",
+			closedness:   ClosednessDefault,
+			content:      "name: web\nnmae: web\nlabels:\n  tema: a\n",
+			wantValid:    false,
+			wantSeverity: map[string]Severity{"nmae": SeverityError},
+		},
+		{
+			name:       "unknown field next to another error",
+			closedness: ClosednessDefault,
+			content:    "name: 1\nnmae: x\n",
+			wantValid:  false,
+			wantSeverity: map[string]Severity{
+				"name": SeverityError,
+				"nmae": SeverityError,
+			},
+			wantCodes: map[string]Code{"name": CodeTypeMismatch},
+		},
+		{
+			name:         "closed enforces open structs",
+			closedness:   ClosednessClosed,
+			content:      "name: web\nlabels:\n  team: a\n  tema: a\n",
+			wantValid:    false,
+			wantSeverity: map[string]Severity{"labels.tema": SeverityError},
+		},
+		{
+			name:         "closed allows pattern constraints and top",
+			closedness:   ClosednessClosed,
+			content:      "name: web\nlimits:\n  cpu: 1\nextra:\n  anything: 1\n",
+			wantValid:    true,
+			wantSeverity: map[string]Severity{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			schemaPath := filepath.Join(tmpDir, "schema.cue")
+			if err := os.WriteFile(schemaPath, []byte(schema), 0644); err != nil {
+				t.Fatalf("failed to write schema file: %v", err)
+			}
+
+			configPath := filepath.Join(tmpDir, "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			opts := DefaultOptions()
+			opts.Closedness = tt.closedness
+			results := ValidateFilesWithOptions(schemaPath, []string{configPath}, opts)
+
+			if results[0].IsValid != tt.wantValid {
+				t.Errorf("IsValid = %v, want %v", results[0].IsValid, tt.wantValid)
+			}
+			if len(results[0].Errors) != len(tt.wantSeverity) {
+				t.Fatalf("got %d errors, want %d: %v", len(results[0].Errors), len(tt.wantSeverity), results[0].Errors)
+			}
+			for _, err := range results[0].Errors {
+				wantCode, ok := tt.wantCodes[err.Field]
+				if !ok {
+					wantCode = CodeFieldNotAllowed
+				}
+				if want := tt.wantSeverity[err.Field]; err.Severity != want || err.Code != wantCode {
+					t.Errorf("field %s: Severity = %q, Code = %q, want %q %q", err.Field, err.Severity, err.Code, want, wantCode)
+				}
+				if err.Line == 0 {
+					t.Errorf("field %s: expected a line number", err.Field)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithMerge(t *testing.T) {
+	schema := `
+		#Config: {
+			name: string
+			replicas: int & <=5
+			environment: "staging" | "production"
+			resources?: {cpu: string, memory: string}
+		}
+	`
+
+	tests := []struct {
+		name       string
+		mode       MergeMode
+		base       string
+		overlay    string
+		wantValid  bool
+		wantFile   string // File of the first error
+		wantLine   int    // Line of the first error
+		wantErrors []string
+	}{
+		{
+			name:      "overlay completes base",
+			base:      "name: web\nreplicas: 2\n",
+			overlay:   "environment: production\n",
+			wantValid: true,
+		},
+		{
+			name:       "error in overlay",
+			base:       "name: web\nreplicas: 2\n",
+			overlay:    "# production\nenvironment: prod\n",
+			wantValid:  false,
+			wantFile:   "overlay.yaml",
+			wantLine:   2,
+			wantErrors: []string{"environment"},
+		},
+		{
+			name:       "conflict between files",
+			base:       "name: web\nreplicas: 2\n",
+			overlay:    "environment: production\nreplicas: 3\n",
+			wantValid:  false,
+			wantFile:   "overlay.yaml",
+			wantLine:   2,
+			wantErrors: []string{"also set in base.yaml:2"},
+		},
+		{
+			name:      "suppression in overlay",
+			base:      "name: web\nreplicas: 2\n",
+			overlay:   "environment: production\n# cint:ignore-next-line\nreplicas: 3\n",
+			wantValid: true,
+		},
+		{
+			name:      "overlay overrides base",
+			mode:      MergeOverride,
+			base:      "name: web\nreplicas: 2\n",
+			overlay:   "environment: production\nreplicas: 3\n",
+			wantValid: true,
+		},
+		{
+			name:       "overridden value is validated",
+			mode:       MergeOverride,
+			base:       "name: web\nreplicas: 2\n",
+			overlay:    "environment: production\nreplicas: 8\n",
+			wantValid:  false,
+			wantFile:   "overlay.yaml",
+			wantLine:   2,
+			wantErrors: []string{"invalid value 8"},
+		},
+		{
+			name:      "structs are merged",
+			mode:      MergeOverride,
+			base:      "name: web\nreplicas: 2\nresources:\n  cpu: 100m\n  memory: 1Gi\n",
+			overlay:   "environment: production\nresources:\n  cpu: 500m\n",
+			wantValid: true,
+		},
+		{
+			name:       "error in base",
+			mode:       MergeOverride,
+			base:       "name: web\nreplicas: 2\nresources:\n  cpu: 1\n  memory: 1Gi\n",
+			overlay:    "environment: production\nresources:\n  memory: 2Gi\n",
+			wantValid:  false,
+			wantFile:   "base.yaml",
+			wantLine:   4,
+			wantErrors: []string{"resources.cpu"},
+		},
+		{
+			name:      "suppression in overridden value",
+			mode:      MergeOverride,
+			base:      "name: web\nreplicas: 2\n",
+			overlay:   "environment: production\n# cint:ignore-next-line\nreplicas: 8\n",
+			wantValid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			t.Chdir(tmpDir)
+
+			files := map[string]string{"schema.cue": schema, "base.yaml": tt.base, "overlay.yaml": tt.overlay}
+			for name, content := range files {
+				if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+
+			opts := DefaultOptions()
+			opts.Merge = []string{"base.yaml", "overlay.yaml"}
+			opts.MergeMode = tt.mode
+			results := ValidateFilesWithOptions("schema.cue", nil, opts)
+
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			result := results[0]
+			if result.FileName != "base.yaml + overlay.yaml" {
+				t.Errorf("FileName = %q", result.FileName)
+			}
+			if result.IsValid != tt.wantValid {
+				t.Fatalf("IsValid = %v, want %v (errors: %v)", result.IsValid, tt.wantValid, result.Errors)
+			}
+			if len(tt.wantErrors) == 0 {
+				return
+			}
+
+			err := result.Errors[0]
+			if err.File != tt.wantFile || err.Line != tt.wantLine {
+				t.Errorf("location = %s:%d, want %s:%d", err.File, err.Line, tt.wantFile, tt.wantLine)
+			}
+			for _, want := range tt.wantErrors {
+				if !strings.Contains(err.Field+" "+err.Problem, want) {
+					t.Errorf("expected error containing %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithCollection(t *testing.T) {
+	schema := `
+		import "list"
+
+		#Config: {
+			name: string
+			port: int
+		}
+
+		#Collection: X={
+			[string]: #Config
+			_names: {for file, c in X {(c.name): file}}
+			_ports: [for c in X {c.port}] & list.UniqueItems()
+		}
+	`
+
+	tests := []struct {
+		name       string
+		schema     string
+		configs    map[string]string
+		wantResult bool // Whether a #Collection result is reported
+		wantValid  bool
+		wantErrors []string
+	}{
+		{
+			name:       "unique names and ports",
+			schema:     schema,
+			configs:    map[string]string{"a.yaml": "name: web\nport: 80\n", "b.yaml": "name: api\nport: 8080\n"},
+			wantResult: true,
+			wantValid:  true,
+		},
+		{
+			name:       "duplicate name and port",
+			schema:     schema,
+			configs:    map[string]string{"a.yaml": "name: web\nport: 80\n", "b.yaml": "name: web\nport: 80\n"},
+			wantResult: true,
+			wantValid:  false,
+			wantErrors: []string{"_names.web", "list.UniqueItems"},
+		},
+		{
+			name:       "invalid config left out",
+			schema:     schema,
+			configs:    map[string]string{"a.yaml": "name: web\nport: 80\n", "b.yaml": "name: web\nport: http\n"},
+			wantResult: true,
+			wantValid:  true,
+		},
+		{
+			name:    "schema without collection",
+			schema:  "#Config: {name: string, port: int}",
+			configs: map[string]string{"a.yaml": "name: web\nport: 80\n", "b.yaml": "name: web\nport: 80\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			t.Chdir(tmpDir)
+
+			files := map[string]string{"schema.cue": tt.schema}
+			maps.Copy(files, tt.configs)
+			for name, content := range files {
+				if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+
+			results := ValidateFiles("schema.cue", []string{"a.yaml", "b.yaml"})
+
+			last := results[len(results)-1]
+			if (last.FileName == "#Collection") != tt.wantResult {
+				t.Fatalf("last result = %q, want #Collection result: %v", last.FileName, tt.wantResult)
+			}
+			if !tt.wantResult {
+				return
+			}
+			if last.IsValid != tt.wantValid {
+				t.Fatalf("IsValid = %v, want %v (errors: %v)", last.IsValid, tt.wantValid, last.Errors)
+			}
+
+			var all strings.Builder
+			for _, err := range last.Errors {
+				all.WriteString(err.Field + " " + err.Problem + "\n")
+			}
+			for _, want := range tt.wantErrors {
+				if !strings.Contains(all.String(), want) {
+					t.Errorf("expected error containing %q, got: %v", want, last.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithReferences(t *testing.T) {
+	schema := `
+		#Config: {
+			services?: [...{
+				name: string
+				team: string @cint(ref="teams.name")
+				dependsOn?: [...string] @cint(ref="services.name")
+			}]
+			teams?: [...{name: string}]
+		}
+	`
+
+	tests := []struct {
+		name       string
+		configs    map[string]string
+		wantValid  bool
+		wantFile   string // File of the first error
+		wantLine   int    // Line of the first error
+		wantErrors []string
+	}{
+		{
+			name: "references defined in other files",
+			configs: map[string]string{
+				"a.yaml": "teams:\n  - name: platform\n",
+				"b.yaml": "services:\n  - name: web\n    team: platform\n    dependsOn: [api]\n  - name: api\n    team: platform\n",
+			},
+			wantValid: true,
+		},
+		{
+			name: "dangling reference",
+			configs: map[string]string{
+				"a.yaml": "teams:\n  - name: platform\n",
+				"b.yaml": "services:\n  - name: web\n    team: platfrom\n",
+			},
+			wantValid:  false,
+			wantFile:   "b.yaml",
+			wantLine:   3,
+			wantErrors: []string{"services.0.team", "reference `platfrom` is not defined by any `teams.name`", "did you mean `platform`?"},
+		},
+		{
+			name: "reference to invalid config",
+			configs: map[string]string{
+				"a.yaml": "teams:\n  - name: platform\n    lead: someone\n",
+				"b.yaml": "services:\n  - name: web\n    team: platform\n",
+			},
+			wantValid:  false,
+			wantFile:   "b.yaml",
+			wantLine:   3,
+			wantErrors: []string{"reference `platform` is not defined by any `teams.name`"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			t.Chdir(tmpDir)
+
+			files := map[string]string{"schema.cue": schema}
+			maps.Copy(files, tt.configs)
+			for name, content := range files {
+				if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+
+			results := ValidateFiles("schema.cue", []string{"a.yaml", "b.yaml"})
+
+			last := results[len(results)-1]
+			if last.FileName != "#Collection" {
+				t.Fatalf("last result = %q, want #Collection", last.FileName)
+			}
+			if last.IsValid != tt.wantValid {
+				t.Fatalf("IsValid = %v, want %v (errors: %v)", last.IsValid, tt.wantValid, last.Errors)
+			}
+			if len(tt.wantErrors) == 0 {
+				return
+			}
+
+			err := last.Errors[0]
+			if err.File != tt.wantFile || err.Line != tt.wantLine || err.Code != CodeDanglingReference {
+				t.Errorf("error = %s:%d %s, want %s:%d %s", err.File, err.Line, err.Code, tt.wantFile, tt.wantLine, CodeDanglingReference)
+			}
+			for _, want := range tt.wantErrors {
+				if !strings.Contains(err.Field+" "+err.Problem, want) {
+					t.Errorf("expected error containing %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithInjection(t *testing.T) {
+	schema := `
+		catalog: teams: [...{name: string}]
+		env: *"dev" | "prod" @tag(env)
+
+		#Config: {
+			team: or([for t in catalog.teams {t.name}])
+			replicas: int & <=[if env == "prod" {10}, 2][0]
+		}
+	`
+	teams := "teams:\n  - name: platform\n  - name: payments\n"
+
+	tests := []struct {
+		name       string
+		config     string
+		data       []string
+		tags       []string
+		wantValid  bool
+		wantErrors []string
+	}{
+		{
+			name:      "value from data file",
+			config:    "team: payments\nreplicas: 2\n",
+			data:      []string{"catalog=teams.yaml"},
+			wantValid: true,
+		},
+		{
+			name:       "value missing from data file",
+			config:     "team: billing\nreplicas: 2\n",
+			data:       []string{"catalog=teams.yaml"},
+			wantValid:  false,
+			wantErrors: []string{"team"},
+		},
+		{
+			name:       "limit without tag",
+			config:     "team: payments\nreplicas: 5\n",
+			data:       []string{"catalog=teams.yaml"},
+			wantValid:  false,
+			wantErrors: []string{"out of bound <=2"},
+		},
+		{
+			name:      "limit with tag",
+			config:    "team: payments\nreplicas: 5\n",
+			data:      []string{"catalog=teams.yaml"},
+			tags:      []string{"env=prod"},
+			wantValid: true,
+		},
+		{
+			name:       "undeclared data field",
+			config:     "team: payments\nreplicas: 2\n",
+			data:       []string{"teams=teams.yaml"},
+			wantValid:  false,
+			wantErrors: []string{`schema does not declare data field "teams"`},
+		},
+		{
+			name:       "unknown tag",
+			config:     "team: payments\nreplicas: 2\n",
+			data:       []string{"catalog=teams.yaml"},
+			tags:       []string{"region=eu"},
+			wantValid:  false,
+			wantErrors: []string{`no tag for "region"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			t.Chdir(tmpDir)
+
+			files := map[string]string{"schema.cue": schema, "teams.yaml": teams, "config.yaml": tt.config}
+			for name, content := range files {
+				if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+
+			opts := DefaultOptions()
+			opts.Data = tt.data
+			opts.Tags = tt.tags
+			results := ValidateFilesWithOptions("schema.cue", []string{"config.yaml"}, opts)
+
+			result := results[0]
+			if result.IsValid != tt.wantValid {
+				t.Fatalf("IsValid = %v, want %v (errors: %v)", result.IsValid, tt.wantValid, result.Errors)
+			}
+			for _, want := range tt.wantErrors {
+				found := false
+				for _, err := range result.Errors {
+					if strings.Contains(err.Field+" "+err.Problem, want) {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("expected error containing %q, got: %v", want, result.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithEnv(t *testing.T) {
+	schema := `
+		#Config: {
+			name: =~"^[a-z]+$"
+			port: int & <65536
+			resources?: {cpu: int}
+		}
+	`
+
+	tests := []struct {
+		name       string
+		config     string
+		env        EnvMode
+		wantValid  bool
+		wantLine   int // Line of the first error
+		wantErrors []string
+	}{
+		{
+			name:       "placeholders as strings",
+			config:     "name: ${NAME}\nport: 8080\n",
+			env:        EnvOff,
+			wantValid:  false,
+			wantLine:   1,
+			wantErrors: []string{`invalid value "${NAME}"`},
+		},
+		{
+			name:      "expand variable and default",
+			config:    "name: ${NAME}\nport: ${PORT:-8080}\n",
+			env:       EnvExpand,
+			wantValid: true,
+		},
+		{
+			name:       "expanded value is validated",
+			config:     "# web service\nname: web\nport: ${BIG_PORT}\n",
+			env:        EnvExpand,
+			wantValid:  false,
+			wantLine:   3,
+			wantErrors: []string{"invalid value 99999"},
+		},
+		{
+			name:       "unset variable",
+			config:     "name: web\nport: 8080${MISSING}\n",
+			env:        EnvExpand,
+			wantValid:  false,
+			wantLine:   2,
+			wantErrors: []string{"environment variable `MISSING` is not set"},
+		},
+		{
+			name:      "placeholders accepted for scalars",
+			config:    "name: ${NAME}\nport: ${PORT}\n",
+			env:       EnvPlaceholder,
+			wantValid: true,
+		},
+		{
+			name:       "placeholder does not hide missing fields",
+			config:     "port: ${PORT}\n",
+			env:        EnvPlaceholder,
+			wantValid:  false,
+			wantLine:   1,
+			wantErrors: []string{"missing required field `name`"},
+		},
+		{
+			name:       "placeholder for struct",
+			config:     "name: web\nport: 8080\nresources: ${RESOURCES}\n",
+			env:        EnvPlaceholder,
+			wantValid:  false,
+			wantLine:   3,
+			wantErrors: []string{"mismatched types string and struct"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			t.Chdir(tmpDir)
+			t.Setenv("NAME", "web")
+			t.Setenv("BIG_PORT", "99999")
+
+			files := map[string]string{"schema.cue": schema, "config.yaml": tt.config}
+			for name, content := range files {
+				if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+
+			opts := DefaultOptions()
+			opts.Env = tt.env
+			results := ValidateFilesWithOptions("schema.cue", []string{"config.yaml"}, opts)
+
+			result := results[0]
+			if result.IsValid != tt.wantValid {
+				t.Fatalf("IsValid = %v, want %v (errors: %v)", result.IsValid, tt.wantValid, result.Errors)
+			}
+			if len(tt.wantErrors) == 0 {
+				return
+			}
+
+			err := result.Errors[0]
+			if err.Line != tt.wantLine {
+				t.Errorf("Line = %d, want %d", err.Line, tt.wantLine)
+			}
+			for _, want := range tt.wantErrors {
+				if !strings.Contains(err.Problem, want) {
+					t.Errorf("expected error containing %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFilesWithTemplates(t *testing.T) {
+	schema := `
+		#Config: {
+			name: =~"^[a-z]+$"
+			replicas: int & <=5
+			env: [string]: =~"^[0-9]+$"
+			ports: [...int]
+		}
+	`
+	template := `# {{ .Values.name }}
+name: {{ .Values.name | lower }}
+replicas: {{ .Values.replicas | default 1 }}
+env:
+{{- range $k, $v := .Values.env }}
+  {{ $k }}: {{ $v | quote }}
+{{- end }}
+ports: {{ toJson .Values.ports }}
+`
+	values := "name: Web\nenv: {A: \"1\", B: \"2\"}\nports: [80, 443]\n"
+
+	tests := []struct {
+		name       string
+		template   string
+		overrides  string // Second values file, merged over the first
+		wantValid  bool
+		wantLine   int // Template line of the first error
+		wantErrors []string
+	}{
+		{
+			name:      "rendered config is valid",
+			template:  template,
+			wantValid: true,
+		},
+		{
+			name:       "error in overridden value",
+			template:   template,
+			overrides:  "replicas: 9\n",
+			wantValid:  false,
+			wantLine:   3,
+			wantErrors: []string{"invalid value 9"},
+		},
+		{
+			name:       "error in range",
+			template:   template,
+			overrides:  "env: {B: two}\n",
+			wantValid:  false,
+			wantLine:   6,
+			wantErrors: []string{`invalid value "two"`},
+		},
+		{
+			name:       "error after range",
+			template:   template,
+			overrides:  "ports: [http]\n",
+			wantValid:  false,
+			wantLine:   8,
+			wantErrors: []string{"ports"},
+		},
+		{
+			name:      "missing value renders empty",
+			template:  "name: web{{ .Values.suffix }}\nreplicas: 1\nenv: {}\nports: []\n",
+			wantValid: true,
+		},
+		{
+			name:       "field of missing value",
+			template:   "name: web\nreplicas: {{ .Values.scaling.replicas }}\n",
+			wantValid:  false,
+			wantLine:   2,
+			wantErrors: []string{"nil pointer evaluating"},
+		},
+		{
+			name:       "template error",
+			template:   "name: {{ .Values.name | nope }}\n",
+			wantValid:  false,
+			wantLine:   1,
+			wantErrors: []string{`function "nope" not defined`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			t.Chdir(tmpDir)
+
+			files := map[string]string{"schema.cue": schema, "app.yaml.tmpl": tt.template, "values.yaml": values, "overrides.yaml": tt.overrides}
+			for name, content := range files {
+				if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+
+			opts := DefaultOptions()
+			opts.Values = []string{"values.yaml", "overrides.yaml"}
+			results := ValidateFilesWithOptions("schema.cue", []string{"app.yaml.tmpl"}, opts)
+
+			result := results[0]
+			if result.IsValid != tt.wantValid {
+				t.Fatalf("IsValid = %v, want %v (errors: %v)", result.IsValid, tt.wantValid, result.Errors)
+			}
+			if len(tt.wantErrors) == 0 {
+				return
+			}
+
+			err := result.Errors[0]
+			if err.Line != tt.wantLine {
+				t.Errorf("Line = %d, want %d (error: %v)", err.Line, tt.wantLine, err)
+			}
+			for _, want := range tt.wantErrors {
+				if !strings.Contains(err.Field+" "+err.Problem, want) {
+					t.Errorf("expected error containing %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
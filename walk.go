@@ -0,0 +1,54 @@
+package main
+
+import (
+	"slices"
+
+	"cuelang.org/go/cue"
+)
+
+// walkFields calls fn for every regular field and list element below v, parents
+// before their children. Children are skipped when fn returns false.
+func walkFields(v cue.Value, fn func(path []cue.Selector, field cue.Value) bool) {
+	walkFieldsAt(v, nil, fn)
+}
+
+// walkFieldsAt walks the fields of v, which is located at path
+func walkFieldsAt(v cue.Value, path []cue.Selector, fn func(path []cue.Selector, field cue.Value) bool) {
+	var iter *cue.Iterator
+	var err error
+
+	switch v.IncompleteKind() {
+	case cue.StructKind:
+		iter, err = v.Fields()
+	case cue.ListKind:
+		var list cue.Iterator
+		list, err = v.List()
+		iter = &list
+	default:
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	for iter.Next() {
+		fieldPath := append(slices.Clip(path), iter.Selector())
+		if fn(fieldPath, iter.Value()) {
+			walkFieldsAt(iter.Value(), fieldPath, fn)
+		}
+	}
+}
+
+// selectorsToField formats a selector path the same way as field paths of errors
+func selectorsToField(path []cue.Selector) string {
+	return formatPath(selectorStrings(path))
+}
+
+// selectorStrings converts selectors to the path elements used by CUE errors
+func selectorStrings(path []cue.Selector) []string {
+	parts := make([]string, len(path))
+	for i, sel := range path {
+		parts[i] = sel.String()
+	}
+	return parts
+}